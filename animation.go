@@ -0,0 +1,254 @@
+package strmctrl
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"time"
+)
+
+const (
+	// defaultGIFDelay is used for GIF frames without a delay, like most browsers do.
+	defaultGIFDelay = 100 * time.Millisecond
+)
+
+// animationFrame is a frame of an animation, prepared to be sent to the device.
+type animationFrame struct {
+	img   image.Image
+	jpg   []byte
+	delay time.Duration
+}
+
+// PlayGIF plays the given animated GIF on the given display button in a separate goroutine. The frames
+// are scaled to fit onto the button and are shown for their delay. The animation loops until the given
+// context is done or the device is closed, the loop count of the GIF is ignored. If a frame cannot be sent,
+// the error is logged and the playback stops. Setting another image on the display button does not stop
+// the playback, cancel the context first.
+func (d *Device) PlayGIF(ctx context.Context, display Control, g *gif.GIF) error {
+	if err := checkDisplay(display); err != nil {
+		return err
+	}
+	if g == nil || len(g.Image) == 0 {
+		return fmt.Errorf("the GIF contains no frames")
+	}
+
+	frames, err := d.prepareGIF(display, g)
+	if err != nil {
+		return err
+	}
+
+	go d.playFrames(ctx, display, frames)
+
+	return nil
+}
+
+// Animate renders the frames of an animation on the given display button at the given rate (frames per
+// second) until the given context is done or the device is closed. The render function is called with the
+// number of the frame, starting at 0. If rendering, encoding, or sending a frame takes longer than the frame
+// interval, the frames in between are skipped, the frame number reflects the elapsed time. Animate blocks
+// until the animation stops and returns the context's error, or the error that occurred while showing a frame.
+// If render returns nil, the frame is skipped.
+func (d *Device) Animate(ctx context.Context, display Control, fps int, render func(frame int) image.Image) error {
+	if err := checkDisplay(display); err != nil {
+		return err
+	}
+	if fps < 1 {
+		return fmt.Errorf("the frame rate must be at least 1 fps, got %d", fps)
+	}
+
+	interval := time.Second / time.Duration(fps)
+	start := time.Now()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	frame := 0
+	for {
+		img := render(frame)
+		if img != nil {
+			jpg, err := d.encodeImage(uint8(display), img)
+			if err != nil {
+				return err
+			}
+			err = d.showFrame(ctx, display, animationFrame{img: img, jpg: jpg})
+			if err != nil {
+				return err
+			}
+		}
+
+		// the ticker drops ticks if the receiver is too slow, so there is no backlog of frames
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.closed:
+			return nil
+		case now := <-tick.C:
+			frame = max(frame+1, int(now.Sub(start)/interval))
+		}
+	}
+}
+
+// prepareGIF composes and encodes all frames of the given GIF for the given display button, honoring the
+// disposal method of each frame.
+func (d *Device) prepareGIF(display Control, g *gif.GIF) ([]animationFrame, error) {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		for _, img := range g.Image {
+			bounds = bounds.Union(img.Bounds())
+		}
+	}
+
+	canvas := image.NewRGBA(bounds)
+	var previous *image.RGBA
+	frames := make([]animationFrame, 0, len(g.Image))
+	for i, img := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+
+		frame := fitImage(cloneRGBA(canvas), d.settings.linearScaling)
+		jpg, err := d.encodeImage(uint8(display), frame)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode GIF frame %d: %w", i, err)
+		}
+		delay := defaultGIFDelay
+		if i < len(g.Delay) && g.Delay[i] > 0 {
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		frames = append(frames, animationFrame{img: frame, jpg: jpg, delay: delay})
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return frames, nil
+}
+
+// playFrames shows the given frames on the given display button in a loop until the context is done
+// or the device is closed.
+func (d *Device) playFrames(ctx context.Context, display Control, frames []animationFrame) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for i := 0; ; i = (i + 1) % len(frames) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.closed:
+			return
+		case <-timer.C:
+		}
+
+		frame := frames[i]
+		timer.Reset(frame.delay)
+		err := d.showFrame(ctx, display, frame)
+		if err != nil && ctx.Err() == nil && !d.isClosed() {
+			d.settings.logger.Printf("cannot show animation frame on display %d: %v", display, err)
+			return
+		}
+	}
+}
+
+func (d *Device) showFrame(ctx context.Context, display Control, frame animationFrame) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.isClosed() {
+		return nil
+	}
+	d.setImageState(uint8(display), frame.img)
+	return d.recoverFrom(d.uploadJPEG(ctx, uint8(display), frame.jpg))
+}
+
+// Blink draws attention to the given display button: it alternates the current image of the display button with
+// its inverted version for the given number of times, each shown for the given interval. Display buttons without an
+// image blink white. Afterwards, the current image is restored, also if the context is done before the blinking is
+// complete. In this case, the context's error is returned.
+func (d *Device) Blink(ctx context.Context, display Control, count int, interval time.Duration) error {
+	if err := checkDisplay(display); err != nil {
+		return err
+	}
+	index := uint8(display)
+
+	d.lock.Lock()
+	highlight := invertImage(d.stateImage(index))
+	d.lock.Unlock()
+
+	defer d.restoreDisplay(context.WithoutCancel(ctx), index)
+
+	for i := 0; i < 2*count; i++ {
+		var err error
+		if i%2 == 0 {
+			err = d.showHighlight(ctx, index, highlight)
+		} else {
+			err = d.restoreDisplay(ctx, index)
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.closed:
+			return ErrClosed
+		case <-time.After(interval):
+		}
+	}
+
+	return nil
+}
+
+// showHighlight shows the given image on the display button with the given index (1-6) without changing its state.
+func (d *Device) showHighlight(ctx context.Context, index uint8, img image.Image) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.recoverFrom(d.uploadImage(ctx, index, img))
+}
+
+// restoreDisplay shows the last set image on the display button with the given index (1-6).
+func (d *Device) restoreDisplay(ctx context.Context, index uint8) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.isClosed() {
+		return nil
+	}
+	return d.recoverFrom(d.uploadState(ctx, index))
+}
+
+// invertImage returns the inverted version of the given image, or a white image if the given image is nil.
+func invertImage(img image.Image) image.Image {
+	if img == nil {
+		return uniformImage(color.White)
+	}
+	bounds := img.Bounds()
+	result := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(result, result.Bounds(), img, bounds.Min, draw.Src)
+	for i := 0; i < len(result.Pix); i += 4 {
+		alpha := result.Pix[i+3]
+		result.Pix[i] = alpha - result.Pix[i]
+		result.Pix[i+1] = alpha - result.Pix[i+1]
+		result.Pix[i+2] = alpha - result.Pix[i+2]
+	}
+	return result
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	result := image.NewRGBA(img.Bounds())
+	copy(result.Pix, img.Pix)
+	return result
+}
@@ -0,0 +1,99 @@
+package strmctrl
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"sync"
+	"testing"
+	"time"
+)
+
+// uploadTransport wraps the transport of the simulator and records every completed upload of an image to the
+// top left display button.
+type uploadTransport struct {
+	*simTransport
+
+	lock    sync.Mutex
+	uploads []upload
+}
+
+type upload struct {
+	time time.Time
+	jpg  []byte
+}
+
+func (t *uploadTransport) Write(ctx context.Context, packet []byte) (int, error) {
+	n, err := t.simTransport.Write(ctx, packet)
+
+	t.simTransport.lock.Lock()
+	jpg := t.simTransport.images[0]
+	t.simTransport.lock.Unlock()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if jpg != nil && (len(t.uploads) == 0 || !bytes.Equal(t.uploads[len(t.uploads)-1].jpg, jpg)) {
+		t.uploads = append(t.uploads, upload{time: time.Now(), jpg: jpg})
+	}
+	return n, err
+}
+
+func TestPlayGIF(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	white := image.NewPaletted(image.Rect(0, 0, ImageSize, ImageSize), palette)
+	for i := range white.Pix {
+		white.Pix[i] = 1
+	}
+	black := image.NewPaletted(image.Rect(0, 0, ImageSize, ImageSize), palette)
+	g := &gif.GIF{
+		Image: []*image.Paletted{white, black},
+		Delay: []int{5, 10}, // in 1/100 s
+	}
+	delays := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond}
+
+	transport := &uploadTransport{simTransport: newSimTransport()}
+	device, err := OpenTransport(transport, WithSettleTime(0), WithKeepAliveInterval(0), WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer device.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = device.PlayGIF(ctx, DisplayTopLeft, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(420 * time.Millisecond)
+	cancel()
+
+	transport.lock.Lock()
+	uploads := transport.uploads
+	transport.lock.Unlock()
+	if len(uploads) < 4 {
+		t.Fatalf("expected at least 4 frames, got %d", len(uploads))
+	}
+	for i, u := range uploads {
+		img, err := jpeg.Decode(bytes.NewReader(u.jpg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gray := color.GrayModel.Convert(img.At(ImageSize/2, ImageSize/2)).(color.Gray).Y
+		frame := i % 2
+		if (frame == 0) != (gray > 0x80) {
+			t.Errorf("upload %d: expected frame %d, got gray value %d", i, frame, gray)
+		}
+		if i == 0 {
+			continue
+		}
+		const tolerance = 25 * time.Millisecond
+		expected := delays[(i-1)%2]
+		actual := u.time.Sub(uploads[i-1].time)
+		if actual < expected-tolerance || actual > expected+tolerance {
+			t.Errorf("upload %d: expected a delay of %v, got %v", i, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package strmctrl
+
+import (
+	"context"
+	"image"
+)
+
+// Batch collects changes of several display buttons that are sent to the device and committed together
+// with Commit. This avoids visible intermediate states and saves USB transfers compared to several calls
+// of SetImage. The methods of Batch are not safe for concurrent use.
+type Batch struct {
+	device *Device
+	images [6]image.Image
+	set    [6]bool
+}
+
+// Begin starts a new batch of changes for the display buttons of the device.
+func (d *Device) Begin() *Batch {
+	return &Batch{device: d}
+}
+
+// SetImage sets the image of the given display button when the batch is committed. The image is checked
+// immediately. A nil image clears the display button.
+func (b *Batch) SetImage(display Control, img image.Image) error {
+	if err := checkDisplay(display); err != nil {
+		return err
+	}
+	if img != nil {
+		err := checkImageSize(img)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.images[display-1] = img
+	b.set[display-1] = true
+	return nil
+}
+
+// ClearButton clears the given display button when the batch is committed.
+func (b *Batch) ClearButton(display Control) error {
+	return b.SetImage(display, nil)
+}
+
+// Commit encodes all images of the batch, sends them to the device, and commits all changes at once.
+// If one of the images cannot be encoded, nothing is sent. Only the display buttons whose image changed
+// are sent to the device. Display buttons whose updates are limited with WithMaxFPS are sent later. After
+// Commit, the batch is empty and can be reused.
+func (b *Batch) Commit(ctx context.Context) error {
+	d := b.device
+	jpgs, err := d.encodeImages(b.images)
+	if err != nil {
+		return err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	images, set := b.images, b.set
+	b.images, b.set = [6]image.Image{}, [6]bool{}
+
+	for i := range set {
+		if set[i] {
+			d.setImageState(uint8(i+1), images[i])
+		}
+	}
+	for i, throttled := range d.throttleUploads(jpgs) {
+		set[i] = set[i] && !throttled
+	}
+	return d.recoverFrom(d.uploadBatch(ctx, jpgs, set))
+}
+
+// uploadBatch sends the given JPEG data of the display buttons that are marked as set and commits the change.
+// Display buttons with nil data are cleared.
+func (d *Device) uploadBatch(ctx context.Context, jpgs [6][]byte, set [6]bool) error {
+	changed := false
+	for i, jpg := range jpgs {
+		index := uint8(i + 1)
+		switch {
+		case !set[i]:
+			continue
+		case jpg == nil:
+			if d.displayedKnown && d.displayed[i] == nil {
+				continue
+			}
+			err := d.sendClear(ctx, index)
+			if err != nil {
+				return err
+			}
+		default:
+			if d.isDisplayed(index, jpg) {
+				continue
+			}
+			err := d.sendJPEG(ctx, index, jpg)
+			if err != nil {
+				return err
+			}
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return d.commit(ctx)
+}
@@ -0,0 +1,154 @@
+package strmctrl
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const (
+	fadeStepInterval = 20 * time.Millisecond
+
+	// DefaultBrightnessGamma is the default exponent of the curve that maps the brightness in percent to
+	// the brightness value of the device.
+	DefaultBrightnessGamma = 2.2
+
+	// DefaultBrightness is the brightness in percent that is used if the brightness was not set yet, and by Reset.
+	DefaultBrightness uint8 = 100
+)
+
+// idleDim dims the display when there was no activity for the configured time, until the device is closed.
+func (d *Device) idleDim() {
+	after := d.settings.idleDimAfter
+	if after <= 0 {
+		return
+	}
+	timer := time.NewTimer(after)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-d.closed:
+			return
+		case <-timer.C:
+		}
+
+		d.lock.Lock()
+		idle := time.Since(d.lastActivity)
+		if idle >= after && !d.dimmed && d.transport != nil {
+			d.dimmed = true
+			ctx, cancel := context.WithTimeout(d.ops, commandTimeout)
+			d.recoverFrom(d.sendBrightness(ctx, d.settings.idleDimLevel))
+			cancel()
+		}
+		next := after - idle
+		if next <= 0 {
+			next = after
+		}
+		d.lock.Unlock()
+
+		timer.Reset(next)
+	}
+}
+
+// wakeUp records the activity of the user and restores the brightness if the display was dimmed.
+func (d *Device) wakeUp() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.lastActivity = time.Now()
+	if !d.dimmed {
+		return
+	}
+	d.dimmed = false
+
+	ctx, cancel := context.WithTimeout(d.ops, commandTimeout)
+	defer cancel()
+	d.recoverFrom(d.sendBrightness(ctx, d.currentBrightness()))
+}
+
+// hasBrightness indicates if the given brightness in percent was set on the device and is still set.
+func (d *Device) hasBrightness(percent uint8) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.brightnessSet && d.brightness == percent
+}
+
+// currentBrightness returns the brightness in percent that was last set, or DefaultBrightness if the brightness
+// was not set yet. The device must be locked.
+func (d *Device) currentBrightness() uint8 {
+	if !d.brightnessSet {
+		return DefaultBrightness
+	}
+	return d.brightness
+}
+
+// sendBrightness sends the given brightness in percent (0-100) to the device, mapped through the gamma curve.
+func (d *Device) sendBrightness(ctx context.Context, percent uint8) error {
+	return d.sendCRTCommand(ctx, "LIG", applyGamma(percent, d.settings.brightnessGamma))
+}
+
+// applyGamma maps the given brightness in percent (0-100) to the brightness value of the device using the
+// given exponent. A brightness above zero is never mapped to zero, so the display does not go dark.
+func applyGamma(percent uint8, gamma float64) uint8 {
+	percent = min(percent, 100)
+	if percent == 0 || gamma == 1 {
+		return percent
+	}
+	value := math.Round(100 * math.Pow(float64(percent)/100, gamma))
+	return uint8(max(1, value))
+}
+
+// ToggleBrightness switches the display off (brightness 0) if it is on, otherwise it restores the last brightness
+// above zero that was set with SetBrightness, or DefaultBrightness. It returns the resulting brightness in percent.
+func (d *Device) ToggleBrightness(ctx context.Context) (uint8, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	brightness := uint8(0)
+	if d.currentBrightness() == 0 {
+		brightness = d.onBrightness
+		if brightness == 0 {
+			brightness = DefaultBrightness
+		}
+	}
+
+	return brightness, d.setBrightness(ctx, brightness)
+}
+
+// FadeBrightness changes the brightness gradually from the current brightness, see GetBrightness, to the given
+// target (0-100) over the given duration. If the context is done before the fade is complete, the brightness stays at
+// the last intermediate value and the context's error is returned.
+func (d *Device) FadeBrightness(ctx context.Context, target uint8, duration time.Duration) error {
+	target = min(target, 100)
+	start := int(d.GetBrightness())
+	steps := int(duration / fadeStepInterval)
+	if steps < 1 {
+		return d.SetBrightness(ctx, target)
+	}
+
+	tick := time.NewTicker(fadeStepInterval)
+	defer tick.Stop()
+
+	last := start
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+
+		brightness := start + (int(target)-start)*i/steps
+		if brightness == last {
+			continue
+		}
+		err := d.SetBrightness(ctx, uint8(brightness))
+		if err != nil {
+			return err
+		}
+		last = brightness
+	}
+
+	return nil
+}
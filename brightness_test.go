@@ -0,0 +1,156 @@
+package strmctrl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApplyGamma(t *testing.T) {
+	tt := []struct {
+		percent  uint8
+		gamma    float64
+		expected uint8
+	}{
+		{0, DefaultBrightnessGamma, 0},
+		{1, DefaultBrightnessGamma, 1},
+		{10, DefaultBrightnessGamma, 1},
+		{25, DefaultBrightnessGamma, 5},
+		{50, DefaultBrightnessGamma, 22},
+		{75, DefaultBrightnessGamma, 53},
+		{100, DefaultBrightnessGamma, 100},
+		{150, DefaultBrightnessGamma, 100},
+		{10, 1.5, 3},
+		{50, 1.5, 35},
+		{0, 1, 0},
+		{50, 1, 50},
+		{100, 1, 100},
+		{150, 1, 100},
+	}
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("%d%%_%v", tc.percent, tc.gamma), func(t *testing.T) {
+			actual := applyGamma(tc.percent, tc.gamma)
+			if actual != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSetBrightness(t *testing.T) {
+	tt := []struct {
+		name     string
+		opts     []Option
+		percent  uint8
+		expected uint8
+	}{
+		{"default gamma", nil, 50, 22},
+		{"linear", []Option{WithLinearBrightness()}, 50, 50},
+		{"off", nil, 0, 0},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sim := newTestSimulator(t, tc.opts...)
+
+			err := sim.SetBrightness(context.Background(), tc.percent)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sim.GetBrightness() != tc.percent {
+				t.Errorf("expected %d%%, got %d%%", tc.percent, sim.GetBrightness())
+			}
+			if sim.RawBrightness() != tc.expected {
+				t.Errorf("expected raw value %d, got %d", tc.expected, sim.RawBrightness())
+			}
+		})
+	}
+}
+
+func TestGetBrightnessUnset(t *testing.T) {
+	sim := newTestSimulator(t)
+
+	if sim.GetBrightness() != DefaultBrightness {
+		t.Errorf("expected %d%%, got %d%%", DefaultBrightness, sim.GetBrightness())
+	}
+
+	brightness, err := sim.ToggleBrightness(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brightness != 0 || sim.GetBrightness() != 0 {
+		t.Errorf("expected the display to be switched off, got %d%%", sim.GetBrightness())
+	}
+}
+
+// brightnessTransport wraps the transport of the simulator and records the raw values of all LIG commands.
+type brightnessTransport struct {
+	*simTransport
+
+	lock   sync.Mutex
+	values []uint8
+}
+
+func (t *brightnessTransport) Write(ctx context.Context, packet []byte) (int, error) {
+	cmd, args, err := parseCRTCommand(packet)
+	if err == nil && cmd == "LIG" {
+		t.lock.Lock()
+		t.values = append(t.values, args[0])
+		t.lock.Unlock()
+	}
+	return t.simTransport.Write(ctx, packet)
+}
+
+func TestFadeBrightness(t *testing.T) {
+	tt := []struct {
+		name   string
+		start  uint8 // zero if the brightness is not set before the fade
+		target uint8
+	}{
+		{"up", 20, 80},
+		{"down", 80, 20},
+		{"off", 50, 0},
+		{"unset", 0, 80},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := &brightnessTransport{simTransport: newSimTransport()}
+			device, err := OpenTransport(transport, WithSettleTime(0), WithKeepAliveInterval(0), WithLinearBrightness(), WithLogger(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer device.Close()
+			ctx := context.Background()
+			if tc.start > 0 {
+				err = device.SetBrightness(ctx, tc.start)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			start := device.GetBrightness()
+
+			err = device.FadeBrightness(ctx, tc.target, 100*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if device.GetBrightness() != tc.target {
+				t.Errorf("expected %d%%, got %d%%", tc.target, device.GetBrightness())
+			}
+			transport.lock.Lock()
+			defer transport.lock.Unlock()
+			if len(transport.values) == 0 || transport.values[len(transport.values)-1] != tc.target {
+				t.Fatalf("expected the fade to end with %d, got %v", tc.target, transport.values)
+			}
+			low, high := min(start, tc.target), max(start, tc.target)
+			for _, value := range transport.values {
+				if value < low || value > high {
+					t.Errorf("expected all values between %d and %d, got %v", low, high, transport.values)
+					break
+				}
+			}
+		})
+	}
+}
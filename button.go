@@ -0,0 +1,158 @@
+package strmctrl
+
+import (
+	"context"
+	"image"
+	"sync"
+)
+
+// ButtonMode defines how a Button reacts to presses.
+type ButtonMode int
+
+const (
+	// Momentary buttons are active while they are pressed.
+	Momentary ButtonMode = iota
+	// Toggle buttons change between active and inactive with every press.
+	Toggle
+)
+
+// Button is a stateful widget that combines a display button, which shows an icon and a label, with a control
+// that is pressed to use the button. The button is highlighted with inverted colors while it is active. A Button
+// receives its events through a Dispatcher, see Bind. The methods of Button are safe for concurrent use.
+type Button struct {
+	device  *Device
+	display Control
+	control Control
+	mode    ButtonMode
+
+	lock    sync.Mutex
+	icon    image.Image
+	label   string
+	opts    ComposeOptions
+	active  bool
+	onPress func(*Button)
+}
+
+// NewButton returns a new Button that is shown on the given display button and is used with the given control.
+// The control may be the display button itself. The button is not drawn until Draw, SetLabel, or SetIcon is called.
+func NewButton(device *Device, display Control, control Control, mode ButtonMode) (*Button, error) {
+	if err := checkDisplay(display); err != nil {
+		return nil, err
+	}
+	return &Button{
+		device:  device,
+		display: display,
+		control: control,
+		mode:    mode,
+	}, nil
+}
+
+// SetLabel sets the label of the button and draws the button.
+func (b *Button) SetLabel(ctx context.Context, label string) error {
+	b.lock.Lock()
+	b.label = label
+	b.lock.Unlock()
+
+	return b.Draw(ctx)
+}
+
+// SetIcon sets the icon of the button and draws the button. A nil icon removes the icon.
+func (b *Button) SetIcon(ctx context.Context, icon image.Image) error {
+	b.lock.Lock()
+	b.icon = icon
+	b.lock.Unlock()
+
+	return b.Draw(ctx)
+}
+
+// SetOptions sets how the icon and the label of the button are arranged, and draws the button.
+func (b *Button) SetOptions(ctx context.Context, opts ComposeOptions) error {
+	b.lock.Lock()
+	b.opts = opts
+	b.lock.Unlock()
+
+	return b.Draw(ctx)
+}
+
+// SetActive sets the state of the button and draws the button. The handler set with OnPress is not called.
+func (b *Button) SetActive(ctx context.Context, active bool) error {
+	b.lock.Lock()
+	b.active = active
+	b.lock.Unlock()
+
+	return b.Draw(ctx)
+}
+
+// Active indicates if the button is active.
+func (b *Button) Active() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.active
+}
+
+// OnPress sets the handler that is called when the control of the button is pressed. The state of the button
+// is already updated when the handler is called.
+func (b *Button) OnPress(handler func(*Button)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.onPress = handler
+}
+
+// Draw shows the current appearance of the button on its display button.
+func (b *Button) Draw(ctx context.Context) error {
+	return b.device.SetImage(ctx, b.display, b.render())
+}
+
+func (b *Button) render() image.Image {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	opts := b.opts
+	if b.active {
+		opts.TextOptions = opts.TextOptions.withDefaults()
+		opts.Foreground, opts.Background = opts.Background, opts.Foreground
+	}
+	return ComposeButton(b.icon, b.label, opts)
+}
+
+// Bind routes the events of the button's control from the given dispatcher to the button.
+func (b *Button) Bind(dispatcher *Dispatcher) {
+	dispatcher.On(b.control, Pressed, b.handlePress)
+	if b.mode == Momentary {
+		dispatcher.On(b.control, Released, b.handleRelease)
+	}
+}
+
+func (b *Button) handlePress(Event) {
+	b.lock.Lock()
+	if b.mode == Toggle {
+		b.active = !b.active
+	} else {
+		b.active = true
+	}
+	onPress := b.onPress
+	b.lock.Unlock()
+
+	b.redraw()
+	if onPress != nil {
+		onPress(b)
+	}
+}
+
+func (b *Button) handleRelease(Event) {
+	b.lock.Lock()
+	b.active = false
+	b.lock.Unlock()
+
+	b.redraw()
+}
+
+// redraw draws the button after a change of its state, errors are logged.
+func (b *Button) redraw() {
+	err := b.Draw(context.Background())
+	if err != nil {
+		b.device.settings.logger.Printf("cannot draw button on display %d: %v", b.display, err)
+	}
+}
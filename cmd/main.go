@@ -22,9 +22,10 @@ var (
 		generateImage(color.RGBA{255, 0, 255, 255}),
 		generateImage(color.RGBA{0, 255, 255, 255}),
 	}
-	brightness uint8 = 50
 )
 
+const initialBrightness uint8 = 50
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
@@ -44,12 +45,12 @@ func main() {
 
 func list() {
 	deviceInfos, err := strmctrl.List()
-	if err != nil {
-		log.Fatal(err)
-	}
 	for _, info := range deviceInfos {
 		fmt.Println(info.String())
 	}
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func monitor(ctx context.Context, serial string) {
@@ -60,7 +61,7 @@ func monitor(ctx context.Context, serial string) {
 	defer device.Close()
 
 	device.Clear(ctx)
-	device.SetBrightness(ctx, brightness)
+	device.SetBrightness(ctx, initialBrightness)
 	device.SetImages(ctx, images)
 
 	events, err := device.ReadEvents(ctx)
@@ -75,7 +76,13 @@ func monitor(ctx context.Context, serial string) {
 		select {
 		case <-ctx.Done():
 			return
-		case e := <-events:
+		case e, ok := <-events:
+			if !ok {
+				if err := device.Err(); err != nil {
+					log.Print(err)
+				}
+				return
+			}
 			handleEvent(ctx, device, e)
 		}
 	}
@@ -94,17 +101,12 @@ func handleEvent(ctx context.Context, d *strmctrl.Device, e strmctrl.Event) {
 		rotateImages(e.Action)
 		d.SetImages(ctx, images)
 	case e.Is(strmctrl.ButtonLeft, strmctrl.Pressed):
-		brightness = uint8(max(0, int(brightness)-10))
+		brightness := uint8(max(0, int(d.GetBrightness())-10))
 		d.SetBrightness(ctx, brightness)
 	case e.Is(strmctrl.ButtonCenter, strmctrl.Pressed):
-		if brightness < 50 {
-			brightness = 100
-		} else {
-			brightness = 0
-		}
-		d.SetBrightness(ctx, brightness)
+		d.ToggleBrightness(ctx)
 	case e.Is(strmctrl.ButtonRight, strmctrl.Pressed):
-		brightness = min(brightness+10, 100)
+		brightness := min(d.GetBrightness()+10, 100)
 		d.SetBrightness(ctx, brightness)
 	}
 }
@@ -0,0 +1,65 @@
+package strmctrl
+
+import (
+	"image"
+	"image/draw"
+)
+
+// ComposeOptions control how ComposeButton arranges the icon and the label.
+type ComposeOptions struct {
+	// TextOptions define the colors, the font, and the alignment of the label. If Size is zero,
+	// the largest size is used that fits the label into one line and into the bottom third of the button.
+	TextOptions
+	// Padding is the distance in pixels between the border of the button and the content,
+	// and between the icon and the label.
+	Padding int
+}
+
+// ComposeButton creates an image for a display button that shows the given icon above the given label.
+// The icon is scaled into the space above the label, keeping its aspect ratio. If the label is empty,
+// the icon fills the whole button. The label is truncated with an ellipsis if it does not fit into one line.
+func ComposeButton(icon image.Image, label string, opts ComposeOptions) image.Image {
+	textOpts := opts.TextOptions.withDefaults()
+	padding := min(max(0, opts.Padding), ImageSize/4)
+
+	img := image.NewRGBA(image.Rect(0, 0, ImageSize, ImageSize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(textOpts.Background), image.Point{}, draw.Src)
+
+	content := image.Rect(padding, padding, ImageSize-padding, ImageSize-padding)
+	iconArea := content
+	if label != "" {
+		lines, scale := layoutLabel(label, textOpts.Font, textOpts.Size, content.Dx(), content.Dy()/3)
+		labelHeight := textOpts.Font.Height * scale
+		labelArea := image.Rect(content.Min.X, content.Max.Y-labelHeight, content.Max.X, content.Max.Y)
+		drawText(img, labelArea, lines, scale, textOpts)
+		iconArea.Max.Y = labelArea.Min.Y - padding
+	}
+
+	if icon != nil {
+		drawScaled(img, fitRect(icon.Bounds().Size(), iconArea), icon, draw.Over, true)
+	}
+
+	return img
+}
+
+// layoutLabel lays out the given label in one line within the given width and height.
+func layoutLabel(label string, font *Font, size int, width, height int) ([][]rune, int) {
+	if font.Width < 1 || font.Height < 1 {
+		return nil, 0
+	}
+	maxScale := max(1, min(width/font.Width, height/font.Height))
+	columns := func(scale int) int { return max(1, (width+scale)/((font.Width+1)*scale)) }
+
+	scale := min(size, maxScale)
+	if size <= 0 {
+		length := len([]rune(label))
+		for scale = maxScale; scale > 1; scale-- {
+			if length <= columns(scale) {
+				break
+			}
+		}
+	}
+
+	lines := wrapText(label, columns(scale))
+	return truncateLines(lines, 1, columns(scale)), scale
+}
@@ -0,0 +1,22 @@
+package strmctrl
+
+import (
+	"context"
+	"image"
+)
+
+// Controller is the common interface of Device and Simulator. Application code can depend on this
+// interface to be independent of the real hardware, while Open still returns the concrete *Device.
+type Controller interface {
+	Descriptor() string
+	Close()
+
+	SetBrightness(ctx context.Context, percent uint8) error
+	Clear(ctx context.Context) error
+	SetImage(ctx context.Context, display Control, img image.Image) error
+	SetImages(ctx context.Context, imgs [6]image.Image) error
+	ReadEvents(ctx context.Context) (<-chan Event, error)
+}
+
+var _ Controller = (*Device)(nil)
+var _ Controller = (*Simulator)(nil)
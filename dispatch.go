@@ -0,0 +1,245 @@
+package strmctrl
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// ChordWindow is the maximum time between the first and the last press of a chord.
+	ChordWindow = 300 * time.Millisecond
+)
+
+type binding struct {
+	control Control
+	action  Action
+}
+
+// Dispatcher invokes the handlers that are bound to a control and action when a matching event occurs.
+// The handlers are invoked one after another in the order of the events, on a separate goroutine, so that
+// slow handlers do not block the reading of events.
+type Dispatcher struct {
+	lock     sync.Mutex
+	handlers map[binding][]func(Event)
+	chords   []*chord
+}
+
+type chord struct {
+	controls []Control
+	handler  func()
+}
+
+// NewDispatcher returns a new Dispatcher without any bound handlers.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		handlers: make(map[binding][]func(Event)),
+	}
+}
+
+// On binds the given handler to the given control and action.
+func (d *Dispatcher) On(control Control, action Action, handler func(Event)) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	key := binding{control, action}
+	d.handlers[key] = append(d.handlers[key], handler)
+}
+
+// OnRotation binds the given handler to the rotation of the given knob in both directions.
+func (d *Dispatcher) OnRotation(control Control, handler func(Event)) {
+	d.On(control, TurnedCW, handler)
+	d.On(control, TurnedCCW, handler)
+}
+
+// OnChord binds the given handler to a chord of controls: the handler is invoked when all given controls
+// are pressed at the same time, and all presses happened within the ChordWindow. The handler is invoked
+// again only after one of the controls was released. The handlers bound to the single controls are still invoked.
+func (d *Dispatcher) OnChord(controls []Control, handler func()) {
+	if len(controls) == 0 {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.chords = append(d.chords, &chord{controls: slices.Clone(controls), handler: handler})
+}
+
+// Unbind removes all handlers that are bound to the given control, including the chords that contain the control.
+func (d *Dispatcher) Unbind(control Control) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for key := range d.handlers {
+		if key.control == control {
+			delete(d.handlers, key)
+		}
+	}
+	d.chords = slices.DeleteFunc(d.chords, func(c *chord) bool {
+		return slices.Contains(c.controls, control)
+	})
+}
+
+// Clear removes all handlers.
+func (d *Dispatcher) Clear() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	clear(d.handlers)
+	d.chords = nil
+}
+
+func (d *Dispatcher) handlersFor(e Event) []func(Event) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return append([]func(Event){}, d.handlers[binding{e.Control, e.Action}]...)
+}
+
+// chordTracker keeps track of the pressed controls to detect chords.
+type chordTracker struct {
+	pressed map[Control]time.Time
+	active  map[*chord]bool
+}
+
+func newChordTracker() *chordTracker {
+	return &chordTracker{
+		pressed: make(map[Control]time.Time),
+		active:  make(map[*chord]bool),
+	}
+}
+
+// update processes the given event and returns the handlers of the chords that are completed by the event.
+func (t *chordTracker) update(e Event, chords []*chord) []func() {
+	switch e.Action {
+	case Released:
+		delete(t.pressed, e.Control)
+		for c := range t.active {
+			if slices.Contains(c.controls, e.Control) {
+				delete(t.active, c)
+			}
+		}
+		return nil
+	case Pressed:
+		pressTime := e.Time
+		if pressTime.IsZero() {
+			pressTime = time.Now()
+		}
+		t.pressed[e.Control] = pressTime
+	default:
+		return nil
+	}
+
+	var result []func()
+	for _, c := range chords {
+		if t.active[c] || !slices.Contains(c.controls, e.Control) || !t.isPressed(c) {
+			continue
+		}
+		t.active[c] = true
+		result = append(result, c.handler)
+	}
+	return result
+}
+
+// isPressed indicates if all controls of the given chord are pressed and were pressed within the ChordWindow.
+func (t *chordTracker) isPressed(c *chord) bool {
+	var first, last time.Time
+	for _, control := range c.controls {
+		pressTime, ok := t.pressed[control]
+		if !ok {
+			return false
+		}
+		if first.IsZero() || pressTime.Before(first) {
+			first = pressTime
+		}
+		if pressTime.After(last) {
+			last = pressTime
+		}
+	}
+	return last.Sub(first) <= ChordWindow
+}
+
+func (d *Dispatcher) chordsSnapshot() []*chord {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return slices.Clone(d.chords)
+}
+
+// Run consumes the given events and dispatches them to the bound handlers until the events channel
+// is closed or the context is done. When the events channel is closed, Run waits until all pending
+// handlers are completed.
+func (d *Dispatcher) Run(ctx context.Context, events <-chan Event) {
+	var queueLock sync.Mutex
+	var queue []func()
+	wake := make(chan struct{}, 1)
+	done := make(chan struct{})
+
+	worker := &sync.WaitGroup{}
+	worker.Add(1)
+	go func() {
+		defer worker.Done()
+		stopping := false
+		for {
+			queueLock.Lock()
+			jobs := queue
+			queue = nil
+			queueLock.Unlock()
+
+			for _, job := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				job()
+			}
+			if len(jobs) > 0 {
+				continue
+			}
+			if stopping {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			case <-done:
+				stopping = true
+			}
+		}
+	}()
+	defer worker.Wait()
+	defer close(done)
+
+	chords := newChordTracker()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			handlers := d.handlersFor(e)
+			chordHandlers := chords.update(e, d.chordsSnapshot())
+			if len(handlers) == 0 && len(chordHandlers) == 0 {
+				continue
+			}
+
+			queueLock.Lock()
+			for _, handler := range handlers {
+				queue = append(queue, func() { handler(e) })
+			}
+			queue = append(queue, chordHandlers...)
+			queueLock.Unlock()
+
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
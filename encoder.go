@@ -0,0 +1,38 @@
+package strmctrl
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"sync"
+)
+
+var jpegBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Encoder encodes the images for the display buttons into the JPEG format that is expected by the device.
+// Implementations must be safe for concurrent use, since the images are encoded in parallel.
+type Encoder interface {
+	Encode(img image.Image) ([]byte, error)
+}
+
+// JPEGEncoder uses the standard library to encode images as JPEG with the given quality (1-100).
+type JPEGEncoder struct {
+	Quality int
+}
+
+func (e JPEGEncoder) Encode(img image.Image) ([]byte, error) {
+	buffer := jpegBuffers.Get().(*bytes.Buffer)
+	defer jpegBuffers.Put(buffer)
+	buffer.Reset()
+
+	opts := jpeg.Options{
+		Quality: e.Quality,
+	}
+	err := jpeg.Encode(buffer, img, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buffer.Bytes()), nil
+}
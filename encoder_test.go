@@ -0,0 +1,45 @@
+package strmctrl
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"testing"
+)
+
+func BenchmarkSetImagesQuality(b *testing.B) {
+	frames := [2][6]image.Image{testTiles(0), testTiles(1)}
+	for _, quality := range []int{50, 80, 90, 100} {
+		b.Run(fmt.Sprintf("quality %d", quality), func(b *testing.B) {
+			var imageBytes int
+			sim := newTestSimulator(b, WithJPEGQuality(quality), WithMetrics(func(m Metric) {
+				if m.Operation == MetricImageData {
+					imageBytes += m.Bytes
+				}
+			}))
+			ctx := context.Background()
+			imageBytes = 0
+
+			for i := 0; b.Loop(); i++ {
+				err := sim.SetImages(ctx, frames[i%2])
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(imageBytes)/float64(b.N), "bytes/frame")
+		})
+	}
+}
+
+func BenchmarkJPEGEncoder(b *testing.B) {
+	encoder := JPEGEncoder{Quality: DefaultJPEGQuality}
+	img := testTiles(0)[0]
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_, err := encoder.Encode(img)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
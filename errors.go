@@ -0,0 +1,35 @@
+package strmctrl
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDeviceNotFound is returned if the device to open is not connected.
+	ErrDeviceNotFound = errors.New("device not found")
+	// ErrNotConnected is returned if the connection to the device was lost.
+	ErrNotConnected = errors.New("device is not connected")
+	// ErrClosed is returned if the device is already closed.
+	ErrClosed = errors.New("device is closed")
+	// ErrNotDisplay is returned if a control that is not a display button is used to show an image.
+	ErrNotDisplay = errors.New("the control is not a display")
+	// ErrInvalidImageSize is returned if an image does not have the size of a display button.
+	ErrInvalidImageSize = errors.New("invalid image size")
+	// ErrImageTooLarge is returned if an encoded image exceeds the maximum size.
+	ErrImageTooLarge = errors.New("the encoded image is too large")
+	// ErrUnknownControl is returned if a received frame contains an unknown control.
+	ErrUnknownControl = errors.New("unknown hw control")
+)
+
+// checkDisplay returns an error if the given control is not a display button.
+func checkDisplay(display Control) error {
+	if !display.IsDisplay() {
+		return fmt.Errorf("%w: %s", ErrNotDisplay, display)
+	}
+	return nil
+}
+
+func imageTooLargeError(size int, maxSize int) error {
+	return fmt.Errorf("%w: %d bytes, the maximum is %d bytes", ErrImageTooLarge, size, maxSize)
+}
@@ -0,0 +1,442 @@
+package strmctrl
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+// eventStage processes the events of the in channel and provides the results on the returned channel.
+// The returned channel is closed when the in channel is closed or the context is done.
+type eventStage func(ctx context.Context, in <-chan Event) <-chan Event
+
+func (s settings) eventStages() []eventStage {
+	var result []eventStage
+	if s.debounceInterval > 0 {
+		result = append(result, debounceStage(s.debounceInterval))
+	}
+	if s.releaseTimeout > 0 {
+		result = append(result, releaseWatchdogStage(s.releaseTimeout))
+	}
+	if s.rotationWindow > 0 {
+		result = append(result, coalesceRotationStage(s.rotationWindow))
+	}
+	if s.longPressThreshold > 0 {
+		result = append(result, longPressStage(s.longPressThreshold, s.exclusiveLongPress))
+	}
+	if s.doublePressWindow > 0 {
+		result = append(result, doublePressStage(s.doublePressWindow))
+	}
+	if s.deliveryPolicy == DeliveryDropOldest {
+		result = append(result, dropOldestStage(max(1, s.eventBuffer)))
+	}
+	return result
+}
+
+// dropOldestStage queues up to the given number of events and never blocks the in channel. If the queue
+// is full, the oldest event is dropped.
+func dropOldestStage(size int) eventStage {
+	return func(ctx context.Context, in <-chan Event) <-chan Event {
+		out := make(chan Event)
+
+		go func() {
+			defer close(out)
+
+			queue := make([]Event, 0, size)
+			for {
+				var next Event
+				var send chan<- Event
+				if len(queue) > 0 {
+					next = queue[0]
+					send = out
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case e, ok := <-in:
+					if !ok {
+						sendEvents(ctx, out, queue...)
+						return
+					}
+					if len(queue) == size {
+						queue = queue[1:]
+					}
+					queue = append(queue, e)
+				case send <- next:
+					queue = queue[1:]
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// debounceStage drops press and release events of a control that follow the last transition of this control
+// faster than the given interval. If the control settled in a different state when the interval expires, this
+// state is provided as trailing event. Rotation events are not debounced.
+func debounceStage(interval time.Duration) eventStage {
+	return func(ctx context.Context, in <-chan Event) <-chan Event {
+		out := make(chan Event)
+
+		go func() {
+			defer close(out)
+
+			current := make(map[Control]Action)
+			stable := make(map[Control]Action)
+			lastTransition := make(map[Control]time.Time)
+			unsettled := make(map[Control]time.Time)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e, ok := <-in:
+					if !ok {
+						return
+					}
+					if !e.Action.IsPress() {
+						if !sendEvents(ctx, out, e) {
+							return
+						}
+						continue
+					}
+
+					current[e.Control] = e.Action
+					stableAction, known := stable[e.Control]
+					if known && stableAction == e.Action {
+						continue
+					}
+					if time.Since(lastTransition[e.Control]) < interval {
+						unsettled[e.Control] = lastTransition[e.Control]
+						continue
+					}
+
+					stable[e.Control] = e.Action
+					lastTransition[e.Control] = time.Now()
+					if !sendEvents(ctx, out, e) {
+						return
+					}
+				case now := <-timerChannel(earliestDeadline(unsettled, interval)):
+					for control, t := range unsettled {
+						if now.Sub(t) < interval {
+							continue
+						}
+						delete(unsettled, control)
+						if current[control] == stable[control] {
+							continue
+						}
+						stable[control] = current[control]
+						lastTransition[control] = now
+						if !sendEvents(ctx, out, Event{Control: control, Action: current[control], Time: now}) {
+							return
+						}
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// releaseWatchdogStage synthesizes a Released event if a control is pressed longer than the given timeout.
+// The real Released event of this control is dropped later.
+func releaseWatchdogStage(timeout time.Duration) eventStage {
+	return func(ctx context.Context, in <-chan Event) <-chan Event {
+		out := make(chan Event)
+
+		go func() {
+			defer close(out)
+
+			pressed := make(map[Control]time.Time)
+			released := make(map[Control]bool)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e, ok := <-in:
+					if !ok {
+						return
+					}
+					switch e.Action {
+					case Pressed:
+						pressed[e.Control] = time.Now()
+						delete(released, e.Control)
+					case Released:
+						delete(pressed, e.Control)
+						if released[e.Control] {
+							delete(released, e.Control)
+							continue
+						}
+					}
+					if !sendEvents(ctx, out, e) {
+						return
+					}
+				case now := <-timerChannel(earliestDeadline(pressed, timeout)):
+					for control, t := range pressed {
+						if now.Sub(t) < timeout {
+							continue
+						}
+						delete(pressed, control)
+						released[control] = true
+						if !sendEvents(ctx, out, Event{Control: control, Action: Released, Time: now}) {
+							return
+						}
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// coalesceRotationStage combines the rotation events of a knob within the given window into a single event
+// that carries the net number of steps. Rotations that cancel each other out are dropped. Any other event of
+// the knob provides the pending rotation first.
+func coalesceRotationStage(window time.Duration) eventStage {
+	return func(ctx context.Context, in <-chan Event) <-chan Event {
+		out := make(chan Event)
+
+		go func() {
+			defer close(out)
+
+			windowStart := make(map[Control]time.Time)
+			pending := make(map[Control]Event)
+			flush := func(control Control) []Event {
+				e, ok := pending[control]
+				delete(pending, control)
+				delete(windowStart, control)
+				if !ok || e.Steps == 0 {
+					return nil
+				}
+				e.Action = TurnedCW
+				if e.Steps < 0 {
+					e.Action = TurnedCCW
+				}
+				return []Event{e}
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e, ok := <-in:
+					if !ok {
+						// provide the pending rotations before closing
+						var rest []Event
+						for _, control := range byTime(windowStart) {
+							rest = append(rest, flush(control)...)
+						}
+						sendEvents(ctx, out, rest...)
+						return
+					}
+					if !e.Action.IsRotation() {
+						if !sendEvents(ctx, out, append(flush(e.Control), e)...) {
+							return
+						}
+						continue
+					}
+
+					last, ok := pending[e.Control]
+					if !ok {
+						windowStart[e.Control] = time.Now()
+					}
+					e.Steps += last.Steps
+					pending[e.Control] = e
+				case now := <-timerChannel(earliestDeadline(windowStart, window)):
+					for control, t := range windowStart {
+						if now.Sub(t) < window {
+							continue
+						}
+						if !sendEvents(ctx, out, flush(control)...) {
+							return
+						}
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// longPressStage synthesizes a LongPressed event when a control is pressed longer than the given threshold.
+// If exclusive is true, the Pressed and Released events of a long press are dropped, and the Pressed event of
+// a short press is delayed until the control is released.
+func longPressStage(threshold time.Duration, exclusive bool) eventStage {
+	return func(ctx context.Context, in <-chan Event) <-chan Event {
+		out := make(chan Event)
+
+		go func() {
+			defer close(out)
+
+			pressed := make(map[Control]time.Time)
+			pressTime := make(map[Control]time.Time)
+			longPressed := make(map[Control]bool)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e, ok := <-in:
+					if !ok {
+						return
+					}
+					var forward []Event
+					switch e.Action {
+					case Pressed:
+						pressed[e.Control] = time.Now()
+						pressTime[e.Control] = e.Time
+						if !exclusive {
+							forward = []Event{e}
+						}
+					case Released:
+						_, pending := pressed[e.Control]
+						delete(pressed, e.Control)
+						switch {
+						case !exclusive:
+							forward = []Event{e}
+						case pending:
+							forward = []Event{{Control: e.Control, Action: Pressed, Time: pressTime[e.Control]}, e}
+						case longPressed[e.Control]:
+							delete(longPressed, e.Control)
+						default:
+							forward = []Event{e}
+						}
+					default:
+						forward = []Event{e}
+					}
+					if !sendEvents(ctx, out, forward...) {
+						return
+					}
+				case now := <-timerChannel(earliestDeadline(pressed, threshold)):
+					for control, t := range pressed {
+						if now.Sub(t) < threshold {
+							continue
+						}
+						delete(pressed, control)
+						longPressed[control] = true
+						if !sendEvents(ctx, out, Event{Control: control, Action: LongPressed, Time: now}) {
+							return
+						}
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// doublePressStage synthesizes a DoublePressed event when a control is pressed twice within the given window.
+// The events of the first press are delayed until the window expires. If the control is pressed again within
+// the window, the events of both presses are replaced by a single DoublePressed event.
+func doublePressStage(window time.Duration) eventStage {
+	return func(ctx context.Context, in <-chan Event) <-chan Event {
+		out := make(chan Event)
+
+		go func() {
+			defer close(out)
+
+			firstPress := make(map[Control]time.Time)
+			delayed := make(map[Control][]Event)
+			doublePressed := make(map[Control]bool)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e, ok := <-in:
+					if !ok {
+						// provide the delayed presses before closing
+						var rest []Event
+						for _, control := range byTime(firstPress) {
+							rest = append(rest, delayed[control]...)
+						}
+						sendEvents(ctx, out, rest...)
+						return
+					}
+					var forward []Event
+					_, pending := firstPress[e.Control]
+					switch {
+					case e.Action == Pressed && pending:
+						delete(firstPress, e.Control)
+						delete(delayed, e.Control)
+						doublePressed[e.Control] = true
+						forward = []Event{{Control: e.Control, Action: DoublePressed, Time: e.Time}}
+					case e.Action == Pressed:
+						firstPress[e.Control] = time.Now()
+						delayed[e.Control] = []Event{e}
+					case e.Action == Released && pending:
+						delayed[e.Control] = append(delayed[e.Control], e)
+					case e.Action == Released && doublePressed[e.Control]:
+						delete(doublePressed, e.Control)
+					default:
+						forward = []Event{e}
+					}
+					if !sendEvents(ctx, out, forward...) {
+						return
+					}
+				case now := <-timerChannel(earliestDeadline(firstPress, window)):
+					for control, t := range firstPress {
+						if now.Sub(t) < window {
+							continue
+						}
+						events := delayed[control]
+						delete(firstPress, control)
+						delete(delayed, control)
+						if !sendEvents(ctx, out, events...) {
+							return
+						}
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// byTime returns the controls of the given map ordered by their times, the earliest first.
+func byTime(times map[Control]time.Time) []Control {
+	result := slices.Collect(maps.Keys(times))
+	slices.SortFunc(result, func(a, b Control) int {
+		return times[a].Compare(times[b])
+	})
+	return result
+}
+
+// earliestDeadline returns the earliest of the given times plus the given duration, or the zero time if there are no times.
+func earliestDeadline(times map[Control]time.Time, duration time.Duration) time.Time {
+	var result time.Time
+	for _, t := range times {
+		if result.IsZero() || t.Before(result) {
+			result = t
+		}
+	}
+	if result.IsZero() {
+		return result
+	}
+	return result.Add(duration)
+}
+
+// timerChannel returns a channel that provides the current time when the given deadline is reached.
+// If the deadline is zero, the returned channel is nil and blocks forever.
+func timerChannel(deadline time.Time) <-chan time.Time {
+	if deadline.IsZero() {
+		return nil
+	}
+	return time.After(time.Until(deadline))
+}
+
+// sendEvents sends the given events to the out channel. It returns false if the context is done before all events were sent.
+func sendEvents(ctx context.Context, out chan<- Event, events ...Event) bool {
+	for _, e := range events {
+		select {
+		case out <- e:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
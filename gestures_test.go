@@ -0,0 +1,114 @@
+package strmctrl
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// runStage feeds the given events into the given stage, waits for the given time, closes the input, and collects
+// all events that are provided until the stage's output is closed.
+func runStage(t *testing.T, stage eventStage, wait time.Duration, events ...Event) []Event {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	in := make(chan Event)
+	out := stage(ctx, in)
+	go func() {
+		defer close(in)
+		for _, e := range events {
+			select {
+			case in <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+		time.Sleep(wait)
+	}()
+
+	var result []Event
+	for e := range out {
+		result = append(result, e)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("the stage did not close its output")
+	}
+	return result
+}
+
+// checkEvents compares the controls and actions of the given events.
+func checkEvents(t *testing.T, expected []Event, actual []Event) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %v", len(expected), len(actual), actual)
+	}
+	for i := range expected {
+		if actual[i].Control != expected[i].Control || actual[i].Action != expected[i].Action {
+			t.Errorf("event %d: expected %v %v, got %v %v", i, expected[i].Control, expected[i].Action, actual[i].Control, actual[i].Action)
+		}
+	}
+}
+
+func TestDebounceStage(t *testing.T) {
+	press := Event{Control: ButtonCenter, Action: Pressed}
+	release := Event{Control: ButtonCenter, Action: Released}
+
+	tt := []struct {
+		name     string
+		events   []Event
+		expected []Event
+	}{
+		{"bounce settles pressed", []Event{press, release, press}, []Event{press}},
+		{"bounce settles released", []Event{press, release, press, release}, []Event{press, release}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// close the input only after the interval expired, so that the trailing state is provided
+			actual := runStage(t, debounceStage(20*time.Millisecond), 50*time.Millisecond, tc.events...)
+
+			checkEvents(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCoalesceRotationStage(t *testing.T) {
+	cw := Event{Control: KnobTop, Action: TurnedCW, Steps: 1}
+	ccw := Event{Control: KnobTop, Action: TurnedCCW, Steps: -1}
+	press := Event{Control: KnobTop, Action: Pressed}
+
+	tt := []struct {
+		name     string
+		events   []Event
+		wait     time.Duration
+		expected []Event
+		steps    []int
+	}{
+		{"window expires", []Event{cw, cw, cw, cw, cw}, 50 * time.Millisecond, []Event{cw}, []int{5}},
+		{"input closed", []Event{cw, cw, cw, cw, cw}, 0, []Event{cw}, []int{5}},
+		{"net rotation", []Event{cw, ccw, ccw, ccw}, 0, []Event{ccw}, []int{-2}},
+		{"cancelled out", []Event{cw, ccw}, 0, nil, nil},
+		{"other event", []Event{cw, cw, press}, 0, []Event{cw, press}, []int{2, 0}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := runStage(t, coalesceRotationStage(20*time.Millisecond), tc.wait, tc.events...)
+
+			checkEvents(t, tc.expected, actual)
+			for i, steps := range tc.steps {
+				if actual[i].Steps != steps {
+					t.Errorf("event %d: expected %d steps, got %d", i, steps, actual[i].Steps)
+				}
+			}
+		})
+	}
+}
+
+func TestDoublePressStageInputClosed(t *testing.T) {
+	press := Event{Control: ButtonLeft, Action: Pressed}
+	release := Event{Control: ButtonLeft, Action: Released}
+
+	actual := runStage(t, doublePressStage(time.Second), 0, press, release)
+
+	checkEvents(t, []Event{press, release}, actual)
+}
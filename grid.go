@@ -0,0 +1,44 @@
+package strmctrl
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+const (
+	// GridColumns is the number of columns of the display buttons.
+	GridColumns = 3
+	// GridRows is the number of rows of the display buttons.
+	GridRows = 2
+
+	// DefaultDisplayGap is the approximate physical gap between two adjacent display buttons in pixels of the
+	// display buttons. On the Stream Controller SE, the gap is roughly a third of the width of a display button.
+	DefaultDisplayGap = 20
+)
+
+// DisplayAt returns the display button at the given column (0-2) and row (0-1), counted from the top left.
+func DisplayAt(col, row int) (Control, error) {
+	if col < 0 || col >= GridColumns || row < 0 || row >= GridRows {
+		return 0, fmt.Errorf("the position %d/%d is outside of the grid of %dx%d display buttons", col, row, GridColumns, GridRows)
+	}
+	return DisplayTopLeft + Control(row*GridColumns+col), nil
+}
+
+// GridPosition returns the column (0-2) and row (0-1) of the given display button, counted from the top left.
+func GridPosition(display Control) (col, row int, err error) {
+	if err := checkDisplay(display); err != nil {
+		return 0, 0, err
+	}
+	index := int(display - DisplayTopLeft)
+	return index % GridColumns, index / GridColumns, nil
+}
+
+// SetImageAt shows the given image on the display button at the given column (0-2) and row (0-1), see SetImage.
+func (d *Device) SetImageAt(ctx context.Context, col, row int, img image.Image) error {
+	display, err := DisplayAt(col, row)
+	if err != nil {
+		return err
+	}
+	return d.SetImage(ctx, display, img)
+}
@@ -0,0 +1,197 @@
+package strmctrl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"  // register the GIF format for LoadImage
+	_ "image/jpeg" // register the JPEG format for LoadImage
+	_ "image/png"  // register the PNG format for LoadImage
+	"math"
+	"os"
+)
+
+// LoadImage reads a PNG, JPEG, or GIF image from the file with the given path. Images that do
+// not have the size of a display button are scaled to fit onto the button, keeping their aspect
+// ratio, and centered on a black background. The images are scaled in linear light, unless the
+// option WithNaiveScaling is given. All other options are ignored.
+func LoadImage(path string, opts ...Option) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load image: %w", err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if errors.Is(err, image.ErrFormat) {
+		return nil, fmt.Errorf("cannot load image %s: only PNG, JPEG, and GIF are supported: %w", path, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot load image %s: %w", path, err)
+	}
+
+	return fitImage(img, newSettings(opts).linearScaling), nil
+}
+
+// SetImageFromBytes decodes the given PNG, JPEG, or GIF data and shows the image on the given display button.
+// Images that do not have the size of a display button are scaled to fit onto the button like with LoadImage.
+// This is useful for images that are embedded into the application or received over the network.
+func (d *Device) SetImageFromBytes(ctx context.Context, display Control, data []byte) error {
+	if err := checkDisplay(display); err != nil {
+		return err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return fmt.Errorf("cannot decode image: only PNG, JPEG, and GIF are supported: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot decode image: %w", err)
+	}
+
+	return d.SetImage(ctx, display, fitImage(img, d.settings.linearScaling))
+}
+
+// fitImage scales the given image to fit onto a display button, if necessary.
+func fitImage(img image.Image, linear bool) image.Image {
+	if img.Bounds().Dx() == ImageSize && img.Bounds().Dy() == ImageSize {
+		return img
+	}
+
+	result := image.NewRGBA(image.Rect(0, 0, ImageSize, ImageSize))
+	draw.Draw(result, result.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	drawScaled(result, fitRect(img.Bounds().Size(), result.Bounds()), img, draw.Over, linear)
+	return result
+}
+
+// flattenImage composes the given image over the given background color, if the image is not opaque. The JPEG
+// format does not support transparency.
+func flattenImage(img image.Image, background color.Color) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+
+	bounds := img.Bounds()
+	result := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(result, result.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(result, result.Bounds(), img, bounds.Min, draw.Over)
+	return result
+}
+
+// fitRect returns the largest rectangle with the aspect ratio of the given size that fits
+// centered into the given area.
+func fitRect(size image.Point, area image.Rectangle) image.Rectangle {
+	if size.X < 1 || size.Y < 1 || area.Empty() {
+		return image.Rectangle{}
+	}
+	width, height := area.Dx(), size.Y*area.Dx()/size.X
+	if height > area.Dy() {
+		width, height = size.X*area.Dy()/size.Y, area.Dy()
+	}
+	width, height = max(width, 1), max(height, 1)
+	min := area.Min.Add(image.Pt((area.Dx()-width)/2, (area.Dy()-height)/2))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(width, height))}
+}
+
+// drawScaled draws the given image scaled into the given rectangle of dst. Every pixel of the
+// rectangle gets the average color of the source pixels that it covers. If linear is true, the colors
+// are averaged in linear light, otherwise the sRGB values are averaged, which is faster, but darkens
+// fine details.
+func drawScaled(dst draw.Image, r image.Rectangle, src image.Image, op draw.Op, linear bool) {
+	srcBounds := src.Bounds()
+	if r.Empty() || srcBounds.Empty() {
+		return
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := range r.Dy() {
+		y0 := srcBounds.Min.Y + y*srcBounds.Dy()/r.Dy()
+		y1 := max(y0+1, srcBounds.Min.Y+(y+1)*srcBounds.Dy()/r.Dy())
+		for x := range r.Dx() {
+			x0 := srcBounds.Min.X + x*srcBounds.Dx()/r.Dx()
+			x1 := max(x0+1, srcBounds.Min.X+(x+1)*srcBounds.Dx()/r.Dx())
+
+			if linear {
+				scaled.SetRGBA64(x, y, averageLinear(src, image.Rect(x0, y0, x1, y1)))
+			} else {
+				scaled.SetRGBA64(x, y, averageSRGB(src, image.Rect(x0, y0, x1, y1)))
+			}
+		}
+	}
+
+	draw.Draw(dst, r, scaled, image.Point{}, op)
+}
+
+// averageSRGB returns the average color of the given area of the given image, averaging the sRGB values.
+func averageSRGB(src image.Image, area image.Rectangle) color.RGBA64 {
+	var sumR, sumG, sumB, sumA, n uint64
+	for sy := area.Min.Y; sy < area.Max.Y; sy++ {
+		for sx := area.Min.X; sx < area.Max.X; sx++ {
+			r, g, b, a := src.At(sx, sy).RGBA()
+			sumR, sumG, sumB, sumA = sumR+uint64(r), sumG+uint64(g), sumB+uint64(b), sumA+uint64(a)
+			n++
+		}
+	}
+	return color.RGBA64{
+		R: uint16(sumR / n),
+		G: uint16(sumG / n),
+		B: uint16(sumB / n),
+		A: uint16(sumA / n),
+	}
+}
+
+// averageLinear returns the average color of the given area of the given image, averaging in linear light.
+// The colors are weighted by their alpha value.
+func averageLinear(src image.Image, area image.Rectangle) color.RGBA64 {
+	var sumR, sumG, sumB, sumA float64
+	n := 0
+	for sy := area.Min.Y; sy < area.Max.Y; sy++ {
+		for sx := area.Min.X; sx < area.Max.X; sx++ {
+			n++
+			r, g, b, a := src.At(sx, sy).RGBA()
+			if a == 0 {
+				continue
+			}
+			alpha := float64(a) / 0xffff
+			sumR += srgbToLinear(float64(r)/float64(a)) * alpha
+			sumG += srgbToLinear(float64(g)/float64(a)) * alpha
+			sumB += srgbToLinear(float64(b)/float64(a)) * alpha
+			sumA += alpha
+		}
+	}
+	if sumA == 0 {
+		return color.RGBA64{}
+	}
+
+	alpha := sumA / float64(n)
+	premultiplied := func(sum float64) uint16 {
+		return uint16(math.Round(linearToSRGB(sum/sumA) * alpha * 0xffff))
+	}
+	return color.RGBA64{
+		R: premultiplied(sumR),
+		G: premultiplied(sumG),
+		B: premultiplied(sumB),
+		A: uint16(math.Round(alpha * 0xffff)),
+	}
+}
+
+// srgbToLinear converts the given sRGB value (0-1) into linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts the given value in linear light (0-1) into sRGB.
+func linearToSRGB(v float64) float64 {
+	v = min(max(0, v), 1)
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
@@ -0,0 +1,172 @@
+package strmctrl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePNG writes the given image as PNG file into a temporary directory and returns the path of the file.
+func writePNG(t *testing.T, img image.Image) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "image.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	err = png.Encode(f, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// averageGray returns the average 8-bit gray value of the given image.
+func averageGray(img image.Image) float64 {
+	bounds := img.Bounds()
+	sum := 0.0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			sum += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+		}
+	}
+	return sum / float64(bounds.Dx()*bounds.Dy())
+}
+
+func TestLoadImageCheckerboard(t *testing.T) {
+	checkerboard := image.NewGray(image.Rect(0, 0, 2*ImageSize, 2*ImageSize))
+	for y := range 2 * ImageSize {
+		for x := range 2 * ImageSize {
+			if (x+y)%2 == 0 {
+				checkerboard.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	path := writePNG(t, checkerboard)
+
+	linear, err := LoadImage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	naive, err := LoadImage(path, WithNaiveScaling())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// half of the light is perceived as sRGB value 188, the average of the sRGB values is 127
+	linearGray := averageGray(linear)
+	naiveGray := averageGray(naive)
+	if linearGray < 185 || linearGray > 191 {
+		t.Errorf("expected a linear average of about 188, got %.1f", linearGray)
+	}
+	if naiveGray < 125 || naiveGray > 130 {
+		t.Errorf("expected a naive average of about 127, got %.1f", naiveGray)
+	}
+}
+
+func TestSetImageWithBackgroundColor(t *testing.T) {
+	translucent := image.NewNRGBA(image.Rect(0, 0, ImageSize, ImageSize))
+	draw.Draw(translucent, translucent.Bounds(), image.NewUniform(color.NRGBA{R: 0xff, A: 0x80}), image.Point{}, draw.Src)
+	var data bytes.Buffer
+	err := png.Encode(&data, translucent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		expected color.RGBA
+	}{
+		{"white", []Option{WithBackgroundColor(color.White)}, color.RGBA{R: 0xff, G: 0x7f, B: 0x7f, A: 0xff}},
+		{"default", nil, color.RGBA{R: 0x80, A: 0xff}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sim := newTestSimulator(t, tc.opts...)
+
+			err := sim.SetImageFromBytes(context.Background(), DisplayTopLeft, data.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, err := sim.Image(DisplayTopLeft)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := color.RGBAModel.Convert(img.At(ImageSize/2, ImageSize/2)).(color.RGBA)
+			if !similarColor(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+// similarColor indicates if the given colors are equal within the tolerance of the JPEG compression.
+func similarColor(a, b color.RGBA) bool {
+	const tolerance = 6
+	near := func(x, y uint8) bool {
+		return max(x, y)-min(x, y) <= tolerance
+	}
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B)
+}
+
+// noiseImage returns an image with random pixels, which compresses badly.
+func noiseImage() image.Image {
+	random := rand.New(rand.NewPCG(1, 2))
+	result := image.NewRGBA(image.Rect(0, 0, ImageSize, ImageSize))
+	for i := range result.Pix {
+		if i%4 == 3 {
+			result.Pix[i] = 0xff
+		} else {
+			result.Pix[i] = uint8(random.UintN(0x100))
+		}
+	}
+	return result
+}
+
+func TestSetImageMaxImageBytes(t *testing.T) {
+	img := noiseImage()
+	full, err := JPEGEncoder{Quality: 100}.Encode(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit := len(full) / 2
+
+	t.Run("reduced quality", func(t *testing.T) {
+		sim := newTestSimulator(t, WithJPEGQuality(100), WithMaxImageBytes(limit))
+
+		err := sim.SetImage(context.Background(), DisplayTopLeft, img)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sim.transport.lock.Lock()
+		size := len(sim.transport.images[0])
+		sim.transport.lock.Unlock()
+		if size == 0 || size > limit {
+			t.Errorf("expected at most %d bytes, got %d", limit, size)
+		}
+	})
+	t.Run("too large", func(t *testing.T) {
+		sim := newTestSimulator(t, WithMaxImageBytes(100))
+
+		err := sim.SetImage(context.Background(), DisplayTopLeft, img)
+
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("expected ErrImageTooLarge, got %v", err)
+		}
+		if displayed, _ := sim.Image(DisplayTopLeft); displayed != nil {
+			t.Error("expected no image on the display")
+		}
+	})
+}
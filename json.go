@@ -0,0 +1,85 @@
+package strmctrl
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MarshalText encodes the control by its name.
+func (c Control) MarshalText() ([]byte, error) {
+	name, ok := controlNames[c]
+	if !ok {
+		return nil, fmt.Errorf("unknown control %d", uint8(c))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes the control from its name.
+func (c *Control) UnmarshalText(text []byte) error {
+	for control, name := range controlNames {
+		if name == string(text) {
+			*c = control
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown control %q", text)
+}
+
+// MarshalText encodes the action by its name.
+func (a Action) MarshalText() ([]byte, error) {
+	name, ok := actionNames[a]
+	if !ok {
+		return nil, fmt.Errorf("unknown action %d", uint8(a))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes the action from its name.
+func (a *Action) UnmarshalText(text []byte) error {
+	for action, name := range actionNames {
+		if name == string(text) {
+			*a = action
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action %q", text)
+}
+
+type jsonEvent struct {
+	Control      Control   `json:"control"`
+	Action       Action    `json:"action"`
+	Time         time.Time `json:"time"`
+	Steps        int       `json:"steps,omitempty"`
+	WhilePressed bool      `json:"whilePressed,omitempty"`
+}
+
+// MarshalJSON encodes the event as JSON object with the names of the control and the action,
+// and the time of the event. The number of steps and the pressed state of rotations are included
+// if set. The raw frame and the velocity are omitted.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonEvent{
+		Control:      e.Control,
+		Action:       e.Action,
+		Time:         e.Time,
+		Steps:        e.Steps,
+		WhilePressed: e.WhilePressed,
+	})
+}
+
+// UnmarshalJSON decodes the event from a JSON object as written by MarshalJSON.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var decoded jsonEvent
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*e = Event{
+		Control:      decoded.Control,
+		Action:       decoded.Action,
+		Time:         decoded.Time,
+		Steps:        decoded.Steps,
+		WhilePressed: decoded.WhilePressed,
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package strmctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/google/gousb"
+)
+
+// Manager opens multiple Stream Controller SE devices that share one USB context. Closing a single
+// device does not affect the other devices of the manager, closing the manager closes all devices.
+// The methods of Manager are safe for concurrent use.
+type Manager struct {
+	usb *gousb.Context
+
+	lock    sync.Mutex
+	devices []*Device
+	closed  bool
+}
+
+// NewManager returns a new Manager with its own USB context.
+func NewManager() *Manager {
+	return &Manager{
+		usb: gousb.NewContext(),
+	}
+}
+
+var errManagerClosed = errors.New("the manager is closed")
+
+// List returns the information of all Stream Controller SE devices that are connected. Only the option
+// WithUSBID is used, all other options are ignored.
+func (m *Manager) List(opts ...Option) ([]DeviceInfo, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return nil, errManagerClosed
+	}
+	return listDevices(m.usb, newSettings(opts).usbID)
+}
+
+// Open the Stream Controller SE device with the given serial number like Open, but using the shared
+// USB context of the manager.
+func (m *Manager) Open(ctx context.Context, serial string, opts ...Option) (*Device, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.open(ctx, selectSerial(serial), opts)
+}
+
+// OpenAll opens all Stream Controller SE devices that are connected, using the shared USB context of the
+// manager. The given options are applied to all devices. If one of the devices cannot be opened, the devices
+// that were already opened by this call are closed again and the error is returned.
+func (m *Manager) OpenAll(ctx context.Context, opts ...Option) ([]*Device, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return nil, errManagerClosed
+	}
+	// devices that cannot be read completely are still opened by their address
+	infos, err := listDevices(m.usb, newSettings(opts).usbID)
+	if err != nil && len(infos) == 0 {
+		return nil, err
+	}
+
+	result := make([]*Device, 0, len(infos))
+	for _, info := range infos {
+		device, err := m.open(ctx, selectAddress(info.Bus, info.Address), opts)
+		if err != nil {
+			for _, device := range result {
+				device.Close()
+			}
+			return nil, fmt.Errorf("cannot open device %s: %w", info.Serial, err)
+		}
+		result = append(result, device)
+	}
+
+	return result, nil
+}
+
+// open the device chosen by the given selector. The lock must be held when calling open.
+func (m *Manager) open(ctx context.Context, selector deviceSelector, opts []Option) (*Device, error) {
+	if m.closed {
+		return nil, errManagerClosed
+	}
+
+	device, err := open(ctx, m.usb, selector, opts)
+	if err != nil {
+		return nil, err
+	}
+	m.devices = append(m.devices, device)
+	return device, nil
+}
+
+// Close all devices that were opened by the manager and release the shared USB context.
+func (m *Manager) Close() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return
+	}
+	m.closed = true
+
+	for _, device := range m.devices {
+		device.Close()
+	}
+	m.devices = nil
+	m.usb.Close()
+}
+
+// Broadcast calls the given function for all given devices concurrently, so that a slow device does not delay
+// the others. It waits until all calls are completed and returns the errors of the devices that failed, or nil
+// if all calls succeeded.
+func Broadcast(devices []*Device, f func(*Device) error) map[*Device]error {
+	var lock sync.Mutex
+	var result map[*Device]error
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := f(device)
+			if err == nil {
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if result == nil {
+				result = make(map[*Device]error)
+			}
+			result[device] = err
+		}()
+	}
+	wg.Wait()
+
+	return result
+}
+
+// BroadcastImages sets the given images on all given devices concurrently, see SetImages and Broadcast.
+func BroadcastImages(ctx context.Context, devices []*Device, imgs [6]image.Image) map[*Device]error {
+	return Broadcast(devices, func(device *Device) error {
+		return device.SetImages(ctx, imgs)
+	})
+}
+
+// BroadcastBrightness sets the given brightness on all given devices concurrently, see SetBrightness and Broadcast.
+func BroadcastBrightness(ctx context.Context, devices []*Device, percent uint8) map[*Device]error {
+	return Broadcast(devices, func(device *Device) error {
+		return device.SetBrightness(ctx, percent)
+	})
+}
+
+// MergedEvent is an event together with the device that produced it.
+type MergedEvent struct {
+	Device *Device
+	Event
+}
+
+// MergeEvents reads the events of all given devices and provides them on a single channel. ReadEvents must
+// not be called for the given devices elsewhere. The channel of a device is closed independently of the
+// others, e.g. if reading fails permanently (see Device.Err). The merged channel is closed when the channels
+// of all devices are closed, or the given context is done.
+func MergeEvents(ctx context.Context, devices ...*Device) (<-chan MergedEvent, error) {
+	// stop reading from all devices if one of them fails
+	ctx, cancel := context.WithCancel(ctx)
+	sources := make([]<-chan Event, len(devices))
+	for i, device := range devices {
+		events, err := device.ReadEvents(ctx)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("cannot read events from %s: %w", device.Descriptor(), err)
+		}
+		sources[i] = events
+	}
+
+	result := make(chan MergedEvent)
+	var wg sync.WaitGroup
+	for i, events := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range events {
+				select {
+				case result <- MergedEvent{Device: devices[i], Event: e}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(result)
+	}()
+
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package strmctrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMergeEvents(t *testing.T) {
+	sims := []*Simulator{newTestSimulator(t), newTestSimulator(t)}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	events, err := MergeEvents(ctx, sims[0].Device, sims[1].Device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sims[1].Inject(Event{Control: KnobTop, Action: TurnedCW})
+
+	select {
+	case e := <-events:
+		if e.Device != sims[1].Device || e.Control != KnobTop || e.Action != TurnedCW {
+			t.Errorf("unexpected event %v from %p", e.Event, e.Device)
+		}
+	case <-ctx.Done():
+		t.Fatal("no event received")
+	}
+}
+
+func TestMergeEventsFailingDevice(t *testing.T) {
+	sims := []*Simulator{newTestSimulator(t), newTestSimulator(t)}
+	sims[1].Close()
+
+	_, err := MergeEvents(context.Background(), sims[0].Device, sims[1].Device)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	// the reader of the first device must be stopped, so that it does not take the events of the next reader
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	events, err := sims[0].ReadEvents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const count = 5
+	for range count {
+		sims[0].Inject(Event{Control: KnobTop, Action: TurnedCW})
+	}
+	for i := range count {
+		select {
+		case <-events:
+		case <-ctx.Done():
+			t.Fatalf("expected %d events, got %d", count, i)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package strmctrl
+
+import (
+	"image/color"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/gousb"
+)
+
+const (
+	// DefaultJPEGQuality is the JPEG quality that is used to encode the button images by default.
+	DefaultJPEGQuality = 100
+
+	// jpegQualityStep is used to lower the JPEG quality if an encoded image is too large.
+	jpegQualityStep = 10
+
+	// DefaultWriteRetries is the default number of retries of a command that failed with a transient USB error.
+	DefaultWriteRetries = 2
+
+	// DefaultSettleTime is the default delay after the handshake with the device. Every Open and every
+	// reconnect takes this long in addition to the handshake, see WithSettleTime.
+	DefaultSettleTime = 50 * time.Millisecond
+
+	// DefaultKeepAliveInterval is the default interval of the pings that keep the connection to the device alive.
+	DefaultKeepAliveInterval = 5 * time.Second
+	// minKeepAliveInterval limits the USB traffic caused by the pings.
+	minKeepAliveInterval = 100 * time.Millisecond
+)
+
+// Logger is used to log diagnostic messages. *log.Logger implements this interface.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...any) {}
+
+// MetricImageData is the operation of a Metric that describes the transfer of the image data of a display button.
+const MetricImageData = "image data"
+
+// Metric describes one operation that was sent to the device, see WithMetrics.
+type Metric struct {
+	Operation string        // the name of the command, e.g. "BAT" or "LIG", or MetricImageData
+	Bytes     int           // the number of bytes that were written, without padding
+	Duration  time.Duration // the duration of the operation, including retries
+	Err       error         // the error of the operation, nil if it succeeded
+}
+
+// DeliveryPolicy defines what happens with new events if the consumer of the channel provided by
+// ReadEvents is not ready to take them.
+type DeliveryPolicy int
+
+const (
+	// DeliveryBlock pauses reading from the device until the consumer takes the next event.
+	DeliveryBlock DeliveryPolicy = iota
+	// DeliveryDropOldest keeps reading from the device and drops the oldest event if the buffer is full.
+	DeliveryDropOldest
+)
+
+// Option configures the behavior of a Device when it is opened.
+type Option func(*settings)
+
+type settings struct {
+	encoder       Encoder
+	maxImageBytes int
+	background    color.Color
+	rotation      int
+	linearScaling bool
+	logger        Logger
+	displayGap    int
+
+	metricsHandler func(Metric)
+
+	settleTime        time.Duration
+	keepAliveInterval time.Duration
+	minUpdateInterval time.Duration
+	writeRetries      int
+	brightnessGamma   float64
+	idleDimAfter      time.Duration
+	idleDimLevel      uint8
+
+	rawEvents      bool
+	blockingReads  bool
+	eventBuffer    int
+	deliveryPolicy DeliveryPolicy
+
+	usb               *gousb.Context
+	usbID             usbID
+	usbReset          bool
+	deviceFilter      func(DeviceInfo) bool
+	autoReconnect     bool
+	restoreState      bool
+	reconnectHandler  func()
+	disconnectHandler func(error)
+
+	debounceInterval   time.Duration
+	releaseTimeout     time.Duration
+	rotationWindow     time.Duration
+	longPressThreshold time.Duration
+	exclusiveLongPress bool
+	doublePressWindow  time.Duration
+}
+
+func defaultSettings() settings {
+	return settings{
+		encoder:       JPEGEncoder{Quality: DefaultJPEGQuality},
+		maxImageBytes: MaxImageBytes,
+		background:    color.Black,
+		linearScaling: true,
+		logger:        log.Default(),
+		displayGap:    DefaultDisplayGap,
+
+		usbID:             usbID{vendor: DefaultVendorID, product: DefaultProductID},
+		usbReset:          true,
+		settleTime:        DefaultSettleTime,
+		keepAliveInterval: DefaultKeepAliveInterval,
+		writeRetries:      DefaultWriteRetries,
+		brightnessGamma:   DefaultBrightnessGamma,
+		restoreState:      true,
+	}
+}
+
+func newSettings(opts []Option) settings {
+	result := defaultSettings()
+	for _, opt := range opts {
+		opt(&result)
+	}
+	return result
+}
+
+// WithJPEGQuality sets the quality (1-100) that is used to encode the button images.
+// Lower values result in smaller USB transfers and faster updates of the display buttons.
+// Values outside of the valid range are clamped. This replaces any encoder set with WithEncoder.
+func WithJPEGQuality(quality int) Option {
+	return func(s *settings) {
+		s.encoder = JPEGEncoder{Quality: min(max(1, quality), 100)}
+	}
+}
+
+// WithEncoder sets the encoder that is used to encode the button images. The encoder must
+// produce JPEG data. By default, a JPEGEncoder with DefaultJPEGQuality is used.
+func WithEncoder(encoder Encoder) Option {
+	return func(s *settings) {
+		if encoder != nil {
+			s.encoder = encoder
+		}
+	}
+}
+
+// WithMaxImageBytes sets the maximum size of an encoded image in bytes (1-MaxImageBytes).
+// If an image encoded with a JPEGEncoder exceeds this size, it is encoded again with a lower
+// quality until it fits. Images that cannot be encoded within this size are rejected with an error.
+func WithMaxImageBytes(size int) Option {
+	return func(s *settings) {
+		s.maxImageBytes = min(max(1, size), MaxImageBytes)
+	}
+}
+
+// WithBackgroundColor sets the color that shows through the transparent parts of the button images. The JPEG
+// format does not support transparency, therefore images that are not opaque are composed over this color
+// before they are encoded. The default is black. A nil color selects the default.
+func WithBackgroundColor(c color.Color) Option {
+	return func(s *settings) {
+		if c == nil {
+			c = color.Black
+		}
+		s.background = c
+	}
+}
+
+// WithRotation rotates the images of all display buttons clockwise by the given degrees before they are sent to
+// the device, e.g. if the device is mounted sideways. The rotation is rounded to a multiple of 90 degrees. The
+// rotation of single display buttons can be changed with SetRotation.
+func WithRotation(degrees int) Option {
+	return func(s *settings) {
+		s.rotation = normalizeRotation(degrees)
+	}
+}
+
+// WithNaiveScaling scales images by averaging their sRGB values, which is faster than the default scaling in
+// linear light, but darkens fine details like in photos. This is used when images are fitted onto the display
+// buttons, e.g. by LoadImage and PlayGIF.
+func WithNaiveScaling() Option {
+	return func(s *settings) {
+		s.linearScaling = false
+	}
+}
+
+// WithMetrics sets a handler that is called after every command and every transfer of image data that was sent
+// to the device, e.g. to monitor the USB throughput. The handler is called synchronously while the device is locked,
+// it must return quickly and must not call any methods of the device.
+func WithMetrics(handler func(Metric)) Option {
+	return func(s *settings) {
+		s.metricsHandler = handler
+	}
+}
+
+// WithDisplayGap sets the physical gap between two adjacent display buttons in pixels of the display buttons.
+// The gap is used to lay out graphics that span several display buttons, like DrawProgress and Snapshot.
+// Change it for hardware revisions with a different geometry. The default is DefaultDisplayGap, negative
+// values are raised to zero.
+func WithDisplayGap(pixels int) Option {
+	return func(s *settings) {
+		s.displayGap = max(0, pixels)
+	}
+}
+
+// WithLogger sets the logger that is used for diagnostic messages. By default, the standard logger
+// of the log package is used. If the given logger is nil, no messages are logged.
+func WithLogger(logger Logger) Option {
+	return func(s *settings) {
+		if logger == nil {
+			s.logger = nopLogger{}
+			return
+		}
+		s.logger = logger
+	}
+}
+
+// WithBrightnessGamma sets the exponent of the curve that maps the brightness in percent to the brightness
+// value of the device. The perceived brightness is not linear, the curve makes the steps of the brightness
+// appear uniform. Values of zero or less select DefaultBrightnessGamma.
+func WithBrightnessGamma(gamma float64) Option {
+	return func(s *settings) {
+		if gamma <= 0 || math.IsNaN(gamma) {
+			gamma = DefaultBrightnessGamma
+		}
+		s.brightnessGamma = gamma
+	}
+}
+
+// WithLinearBrightness sends the brightness in percent to the device without any mapping.
+func WithLinearBrightness() Option {
+	return func(s *settings) {
+		s.brightnessGamma = 1
+	}
+}
+
+// WithIdleDim dims the display to the given brightness in percent (0-100) if there were no events for the
+// given time. The next event restores the brightness that was last set with SetBrightness, or 100% if the
+// brightness was not set yet. SetBrightness also restores the brightness and restarts the idle time. The
+// events are only noticed while the channel provided by ReadEvents is consumed.
+func WithIdleDim(after time.Duration, level uint8) Option {
+	return func(s *settings) {
+		s.idleDimAfter = after
+		s.idleDimLevel = min(level, 100)
+	}
+}
+
+// WithMaxFPS limits how often a single display button is updated with SetImage, SetImageJPEG, SetImages,
+// SetAllImages, or a Batch to the given number of frames per second. If a display button is updated faster,
+// the intermediate images are dropped and the latest image is sent when the interval since the last update
+// expired. Clearing a display button is not limited. This protects the device from runaway render loops.
+// Zero or less disables the limit, which is the default.
+func WithMaxFPS(fps int) Option {
+	return func(s *settings) {
+		if fps <= 0 {
+			s.minUpdateInterval = 0
+			return
+		}
+		s.minUpdateInterval = time.Second / time.Duration(fps)
+	}
+}
+
+// WithWriteRetries sets how often a command is repeated if it failed with a transient USB error, like a busy
+// or timed out endpoint. The delay between the retries increases with every retry. Other errors, and errors
+// caused by the context of the operation, are returned immediately. Zero disables the retries. The default is
+// DefaultWriteRetries.
+func WithWriteRetries(retries int) Option {
+	return func(s *settings) {
+		s.writeRetries = max(0, retries)
+	}
+}
+
+// WithSettleTime sets a fixed delay after the handshake with the device, followed by a second CONNECT command.
+// The device's answer is not read, the delay only gives the firmware time to get ready: directly after the
+// handshake, it may drop the first images, especially on a cold start. This applies to opening and reconnecting
+// the device. The default is DefaultSettleTime, zero or less disables the delay and the second command.
+func WithSettleTime(settleTime time.Duration) Option {
+	return func(s *settings) {
+		s.settleTime = settleTime
+	}
+}
+
+// WithKeepAliveInterval sets the interval of the pings that keep the connection to the device alive
+// and detect a lost connection. The default is DefaultKeepAliveInterval. Intervals below 100ms are
+// raised to 100ms. An interval of zero or less disables the pings.
+func WithKeepAliveInterval(interval time.Duration) Option {
+	return func(s *settings) {
+		if interval > 0 {
+			interval = max(interval, minKeepAliveInterval)
+		}
+		s.keepAliveInterval = interval
+	}
+}
+
+// WithUSBContext lets Open and List use the given USB context instead of creating their own. This is useful
+// if the application uses the context for other USB devices, too. The given context stays owned by the caller:
+// closing the device does not close the context, and the caller must close the context only after all devices
+// that use it are closed. Manager ignores this option, it always uses its own context.
+func WithUSBContext(usb *gousb.Context) Option {
+	return func(s *settings) {
+		s.usb = usb
+	}
+}
+
+// WithUSBID sets the USB vendor and product ID of the devices to open or to list, e.g. for compatible devices
+// that use a different USB ID. The default is DefaultVendorID and DefaultProductID. If the option is given
+// several times, the last one wins. A zero ID keeps the default.
+func WithUSBID(vendor, product gousb.ID) Option {
+	return func(s *settings) {
+		s.usbID = usbID{vendor: DefaultVendorID, product: DefaultProductID}
+		if vendor != 0 {
+			s.usbID.vendor = vendor
+		}
+		if product != 0 {
+			s.usbID.product = product
+		}
+	}
+}
+
+// WithoutReset skips the reset of the USB device when it is opened or reconnected. The reset may cause the
+// device to re-enumerate, which delays the start, especially when the device is re-opened quickly. Use this only
+// if the device is known to be in a good state: without the reset, a device that is stuck in a bad state may not
+// answer the handshake, and opening it fails.
+func WithoutReset() Option {
+	return func(s *settings) {
+		s.usbReset = false
+	}
+}
+
+// WithDeviceFilter sets a filter that chooses the device to open among the enumerated devices, in addition to
+// the serial number or the address given to Open or OpenByAddress. The filter receives the same DeviceInfo that
+// List provides. This is useful if several devices have a blank or the same serial number. The filter is also
+// used to find the device again when it is reconnected.
+func WithDeviceFilter(filter func(DeviceInfo) bool) Option {
+	return func(s *settings) {
+		s.deviceFilter = filter
+	}
+}
+
+// WithAutoReconnect enables the automatic reconnection to the device when the connection was lost.
+// The device is re-opened using the same serial number, and the last brightness and images are
+// restored (see WithoutStateRestore). The channel provided by ReadEvents stays open while the device
+// is reconnected.
+func WithAutoReconnect() Option {
+	return func(s *settings) {
+		s.autoReconnect = true
+	}
+}
+
+// WithoutStateRestore disables the restoration of the last brightness and images after the device was
+// reconnected. Use this if the application redraws the display buttons itself, e.g. in the handler set
+// with WithReconnectHandler. Refresh still sends the last set images.
+func WithoutStateRestore() Option {
+	return func(s *settings) {
+		s.restoreState = false
+	}
+}
+
+// WithReconnectHandler sets a handler that is called in a separate goroutine after the device
+// was reconnected successfully. This can be used to re-sync the application's state with the device.
+func WithReconnectHandler(handler func()) Option {
+	return func(s *settings) {
+		s.reconnectHandler = handler
+	}
+}
+
+// WithDisconnectHandler sets a handler that is called in a separate goroutine when the connection to the
+// device was lost, either because the periodic ping or reading the events failed. The handler is called only
+// once per lost connection, not for every failed attempt to reconnect. This works with and without WithAutoReconnect.
+func WithDisconnectHandler(handler func(error)) Option {
+	return func(s *settings) {
+		s.disconnectHandler = handler
+	}
+}
+
+// WithLongPress enables the detection of long presses: if a control is pressed longer than the
+// given threshold, ReadEvents provides an additional event with the action LongPressed. The Pressed
+// and Released events are still provided. A release before the threshold cancels the long press.
+func WithLongPress(threshold time.Duration) Option {
+	return func(s *settings) {
+		s.longPressThreshold = threshold
+		s.exclusiveLongPress = false
+	}
+}
+
+// WithExclusiveLongPress enables the detection of long presses like WithLongPress, but a long press
+// consumes the Pressed and Released events of the control. The Pressed event of a short press is
+// delayed until the control is released.
+func WithExclusiveLongPress(threshold time.Duration) Option {
+	return func(s *settings) {
+		s.longPressThreshold = threshold
+		s.exclusiveLongPress = true
+	}
+}
+
+// WithDoublePress enables the detection of double presses: if a control is pressed twice within
+// the given window, ReadEvents provides a single event with the action DoublePressed instead of the
+// Pressed and Released events of both presses. The events of a single press are delayed until the
+// window expires.
+func WithDoublePress(window time.Duration) Option {
+	return func(s *settings) {
+		s.doublePressWindow = window
+	}
+}
+
+// WithDebounce enables debouncing of the press and release events: transitions of a control that
+// follow the previous transition of this control faster than the given interval are dropped.
+// Rotation events are not affected.
+func WithDebounce(interval time.Duration) Option {
+	return func(s *settings) {
+		s.debounceInterval = interval
+	}
+}
+
+// WithReleaseWatchdog enables a watchdog that provides a Released event if a control is pressed longer
+// than the given timeout, in case the Released event of the device got lost. The Released event that the
+// device sends later is dropped. The timeout must be longer than any intentional long press.
+func WithReleaseWatchdog(timeout time.Duration) Option {
+	return func(s *settings) {
+		s.releaseTimeout = timeout
+	}
+}
+
+// WithRotationCoalescing combines the rotation events of a knob within the given window into a single
+// event. The Steps field of the event contains the net number of steps, the Action is TurnedCW or TurnedCCW
+// according to the direction of the net rotation. If the rotations cancel each other out, no event is provided.
+func WithRotationCoalescing(window time.Duration) Option {
+	return func(s *settings) {
+		s.rotationWindow = window
+	}
+}
+
+// WithEventBuffer sets the number of events that are buffered if the consumer of the channel provided by
+// ReadEvents is slow. Without a buffer, reading from the device pauses until the consumer takes the next event.
+// A buffer lets the device be polled steadily, so no events get lost while the consumer is busy for a short
+// time, but buffered events are delivered with a delay. By default, events are not buffered.
+func WithEventBuffer(size int) Option {
+	return func(s *settings) {
+		s.eventBuffer = max(0, size)
+	}
+}
+
+// WithDeliveryPolicy sets what happens with new events if the consumer of the channel provided by ReadEvents
+// is not ready to take them. With DeliveryDropOldest, the events are buffered as set with WithEventBuffer, but at
+// least one event. By default, DeliveryBlock is used.
+func WithDeliveryPolicy(policy DeliveryPolicy) Option {
+	return func(s *settings) {
+		s.deliveryPolicy = policy
+	}
+}
+
+// WithBlockingReads lets ReadEvents read the next frame as soon as the previous read returned, instead of
+// pacing the reads with the poll interval of the IN endpoint (see PollInterval). The reads block until the
+// device reports an event, so the events are delivered without the additional delay of up to one poll interval
+// between the reads. The reading goroutine is busier in return. By default, the reads are paced.
+func WithBlockingReads() Option {
+	return func(s *settings) {
+		s.blockingReads = true
+	}
+}
+
+// WithRawEvents adds a copy of the received frame to every event provided by ReadEvents.
+// Frames that cannot be decoded are logged. This is useful to analyze the data sent by the device.
+func WithRawEvents() Option {
+	return func(s *settings) {
+		s.rawEvents = true
+	}
+}
@@ -0,0 +1,95 @@
+package strmctrl
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// Page is a layout of the display buttons with its brightness.
+type Page struct {
+	Images [6]image.Image
+	// Brightness points to the brightness in percent (0-100) that is set when the page is shown. If it is nil,
+	// the current brightness is kept.
+	Brightness *uint8
+}
+
+// Pages holds named pages of a device and switches between them. The methods of Pages are safe for concurrent use.
+type Pages struct {
+	device *Device
+
+	lock    sync.Mutex
+	pages   map[string]Page
+	current string
+}
+
+// NewPages returns a new set of pages without any pages for the given device.
+func NewPages(device *Device) *Pages {
+	return &Pages{
+		device: device,
+		pages:  make(map[string]Page),
+	}
+}
+
+// Define adds the given page with the given name, or replaces the page with this name. If the page is currently
+// shown, the change becomes visible with the next call of SwitchPage.
+func (p *Pages) Define(name string, page Page) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pages[name] = page
+}
+
+// Remove removes the page with the given name.
+func (p *Pages) Remove(name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.pages, name)
+}
+
+// Current returns the name of the page that was last shown with SwitchPage, or an empty string.
+func (p *Pages) Current() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.current
+}
+
+// SwitchPage shows the page with the given name. Only the display buttons that differ from the
+// current contents are sent to the device, see SetImages.
+func (p *Pages) SwitchPage(ctx context.Context, name string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	page, ok := p.pages[name]
+	if !ok {
+		return fmt.Errorf("unknown page %q", name)
+	}
+
+	err := p.device.SetImages(ctx, page.Images)
+	if err != nil {
+		return fmt.Errorf("cannot switch to page %q: %w", name, err)
+	}
+	if page.Brightness != nil && !p.device.hasBrightness(*page.Brightness) {
+		err = p.device.SetBrightness(ctx, *page.Brightness)
+		if err != nil {
+			return fmt.Errorf("cannot switch to page %q: %w", name, err)
+		}
+	}
+	p.current = name
+
+	return nil
+}
+
+// Bind switches to the page with the given name when the given control is pressed. The events are received
+// through the given dispatcher. Errors are logged.
+func (p *Pages) Bind(dispatcher *Dispatcher, control Control, name string) {
+	dispatcher.On(control, Pressed, func(Event) {
+		err := p.SwitchPage(context.Background(), name)
+		if err != nil {
+			p.device.settings.logger.Printf("%v", err)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package strmctrl
+
+import (
+	"context"
+	"image"
+	"sync"
+	"testing"
+)
+
+func TestSwitchPageBrightness(t *testing.T) {
+	dim, off := uint8(30), uint8(0)
+	tt := []struct {
+		name     string
+		preset   uint8 // zero if the brightness is not set before switching
+		page     Page
+		expected uint8
+		sent     bool
+	}{
+		{"keep on a fresh device", 0, Page{Images: testTiles(0)}, DefaultBrightness, false},
+		{"keep", 70, Page{Images: testTiles(0)}, 70, false},
+		{"off on a fresh device", 0, Page{Brightness: &off}, 0, true},
+		{"off", 70, Page{Brightness: &off}, 0, true},
+		{"dim", 70, Page{Brightness: &dim}, dim, true},
+		{"unchanged", dim, Page{Brightness: &dim}, dim, false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			sent := false
+			sim := newTestSimulator(t, WithMetrics(func(m Metric) {
+				lock.Lock()
+				defer lock.Unlock()
+				sent = sent || m.Operation == "LIG"
+			}))
+			ctx := context.Background()
+			if tc.preset > 0 {
+				err := sim.SetBrightness(ctx, tc.preset)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			lock.Lock()
+			sent = false
+			lock.Unlock()
+			pages := NewPages(sim.Device)
+			pages.Define("page", tc.page)
+
+			err := pages.SwitchPage(ctx, "page")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sim.GetBrightness() != tc.expected {
+				t.Errorf("expected %d%%, got %d%%", tc.expected, sim.GetBrightness())
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if sent != tc.sent {
+				t.Errorf("expected LIG sent: %t, got %t", tc.sent, sent)
+			}
+		})
+	}
+}
+
+func TestSwitchPageUnknown(t *testing.T) {
+	sim := newTestSimulator(t)
+	pages := NewPages(sim.Device)
+	pages.Define("page", Page{Images: [6]image.Image{}})
+
+	err := pages.SwitchPage(context.Background(), "other")
+
+	if err == nil {
+		t.Error("expected an error for an unknown page")
+	}
+	if pages.Current() != "" {
+		t.Errorf("expected no current page, got %q", pages.Current())
+	}
+}
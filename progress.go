@@ -0,0 +1,65 @@
+package strmctrl
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// ProgressOptions control how DrawProgress renders the progress bar.
+type ProgressOptions struct {
+	// Filled is the color of the filled part of the bar, white by default.
+	Filled color.Color
+	// Empty is the color of the empty part of the bar, black by default.
+	Empty color.Color
+	// Gap is the physical gap between two adjacent display buttons in pixels. If zero, the gap set
+	// with WithDisplayGap is used. A negative value means no gap.
+	Gap int
+}
+
+// DrawProgress shows a horizontal progress bar that spans the three columns of the display buttons in both rows.
+// The given fraction (0-1) of the bar is filled. The gaps between the display buttons are taken into
+// account, so that the bar appears continuous. Only the display buttons that change are sent to the device.
+func (d *Device) DrawProgress(ctx context.Context, fraction float64, opts ProgressOptions) error {
+	return d.SetImages(ctx, progressImages(fraction, opts, d.settings.displayGap))
+}
+
+func progressImages(fraction float64, opts ProgressOptions, defaultGap int) [6]image.Image {
+	filled := opts.Filled
+	if filled == nil {
+		filled = color.White
+	}
+	empty := opts.Empty
+	if empty == nil {
+		empty = color.Black
+	}
+	gap := opts.Gap
+	if gap == 0 {
+		gap = defaultGap
+	}
+	gap = max(0, gap)
+	if math.IsNaN(fraction) {
+		fraction = 0
+	}
+	fraction = min(max(0, fraction), 1)
+
+	const columns = GridColumns
+	totalWidth := columns*ImageSize + (columns-1)*gap
+	filledWidth := int(math.Round(fraction * float64(totalWidth)))
+
+	var result [6]image.Image
+	for column := range columns {
+		offset := column * (ImageSize + gap)
+		width := min(max(0, filledWidth-offset), ImageSize)
+
+		img := image.NewRGBA(image.Rect(0, 0, ImageSize, ImageSize))
+		draw.Draw(img, img.Bounds(), image.NewUniform(empty), image.Point{}, draw.Src)
+		draw.Draw(img, image.Rect(0, 0, width, ImageSize), image.NewUniform(filled), image.Point{}, draw.Src)
+
+		result[column] = img
+		result[column+columns] = img
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package strmctrl
+
+import (
+	"bytes"
+	"context"
+)
+
+// rawFrameBuffer is the number of frames that are buffered for a subscriber of RawFrames.
+const rawFrameBuffer = 16
+
+// SendRawCommand sends the given CRT command with the given arguments to the device.
+// The command and its arguments are not validated, and the state of the Device is not
+// updated. This is meant for advanced use, e.g. to experiment with undocumented commands.
+func (d *Device) SendRawCommand(ctx context.Context, cmd string, args ...byte) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.sendCRTCommand(ctx, cmd, args...)
+}
+
+// ReadRawFrame reads one frame from the IN endpoint of the device, e.g. to receive the reply to a
+// command sent with SendRawCommand. ReadRawFrame must not be used while ReadEvents is active, since
+// the frame would be missing in the event stream.
+func (d *Device) ReadRawFrame(ctx context.Context) ([]byte, error) {
+	d.lock.Lock()
+	transport := d.transport
+	d.lock.Unlock()
+
+	if transport == nil {
+		return nil, ErrNotConnected
+	}
+
+	buf := make([]byte, transport.InEndpoint().MaxPacketSize)
+	n, err := transport.Read(ctx, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// RawFrames provides a copy of every frame that the event loop of ReadEvents receives from the IN endpoint,
+// before it is decoded. This allows to observe the input of the device, e.g. to implement mappings for
+// unknown controls. The frames are only received while ReadEvents is active. If the consumer of the channel
+// is too slow, frames are dropped, so that the event loop is not blocked. The channel is closed when the given
+// context is done or the device is closed.
+func (d *Device) RawFrames(ctx context.Context) (<-chan []byte, error) {
+	if d.isClosed() {
+		return nil, ErrClosed
+	}
+
+	frames := make(chan []byte, rawFrameBuffer)
+	d.rawLock.Lock()
+	if d.rawFrames == nil {
+		d.rawFrames = make(map[chan []byte]struct{})
+	}
+	d.rawFrames[frames] = struct{}{}
+	d.rawLock.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-d.closed:
+		}
+
+		d.rawLock.Lock()
+		defer d.rawLock.Unlock()
+		delete(d.rawFrames, frames)
+		close(frames)
+	}()
+
+	return frames, nil
+}
+
+// publishRawFrame sends a copy of the given frame to all subscribers of RawFrames without blocking.
+func (d *Device) publishRawFrame(frame []byte) {
+	d.rawLock.Lock()
+	defer d.rawLock.Unlock()
+
+	for frames := range d.rawFrames {
+		select {
+		case frames <- bytes.Clone(frame):
+		default:
+		}
+	}
+}
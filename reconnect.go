@@ -0,0 +1,155 @@
+package strmctrl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"time"
+
+	"github.com/google/gousb"
+)
+
+const (
+	reconnectInterval = 1 * time.Second
+	reconnectTimeout  = 5 * time.Second
+)
+
+// recoverFrom tries to reconnect the device if auto-reconnect is enabled and the given error
+// indicates that the connection to the device was lost. It returns nil if the device was reconnected
+// successfully, otherwise the given error is returned. The lock must be held when calling recoverFrom.
+func (d *Device) recoverFrom(err error) error {
+	if err == nil || d.isClosed() || !isConnectionError(err) {
+		return err
+	}
+	d.notifyDisconnect(err)
+	if !d.settings.autoReconnect {
+		return err
+	}
+
+	reconnectErr := d.reconnect()
+	if reconnectErr != nil {
+		return errors.Join(err, reconnectErr)
+	}
+	return nil
+}
+
+// reconnect re-opens the USB device, initializes it, and restores the last state of the display buttons.
+// The lock is held while reconnecting, so no events are read before the state is restored.
+func (d *Device) reconnect() error {
+	d.disconnect()
+
+	ctx, cancel := context.WithTimeout(d.ops, reconnectTimeout)
+	defer cancel()
+
+	if d.dial == nil {
+		return errors.New("cannot reconnect: reconnecting is not supported by the transport")
+	}
+	err := d.connect(ctx, d.dial)
+	if err != nil {
+		return fmt.Errorf("cannot reconnect: %w", err)
+	}
+
+	d.dimmed = false
+	d.lastActivity = time.Now()
+	if d.settings.restoreState {
+		err = d.restoreState(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot restore state after reconnect: %w", err)
+		}
+	}
+
+	if d.settings.reconnectHandler != nil {
+		go d.settings.reconnectHandler()
+	}
+
+	return nil
+}
+
+// notifyDisconnect calls the disconnect handler in a separate goroutine, but only once until the device
+// is connected again. The lock must be held when calling notifyDisconnect.
+func (d *Device) notifyDisconnect(err error) {
+	if d.disconnectNotified {
+		return
+	}
+	d.disconnectNotified = true
+	if d.settings.disconnectHandler != nil {
+		go d.settings.disconnectHandler(err)
+	}
+}
+
+// restoreState sends the last known brightness and images to the device.
+func (d *Device) restoreState(ctx context.Context) error {
+	if d.brightnessSet {
+		err := d.sendBrightness(ctx, d.brightness)
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.refreshImages(ctx)
+}
+
+// setImageState remembers the given image of the display button with the given index (1-6) to restore it after a reconnect.
+func (d *Device) setImageState(index uint8, img image.Image) {
+	if index == allDisplays {
+		clear(d.images[:])
+		clear(d.jpegs[:])
+		return
+	}
+	d.images[index-1] = img
+	d.jpegs[index-1] = nil
+}
+
+// setJPEGState remembers the given JPEG data of the display button with the given index (1-6) to restore it after a reconnect.
+func (d *Device) setJPEGState(index uint8, jpg []byte) {
+	d.images[index-1] = nil
+	d.jpegs[index-1] = bytes.Clone(jpg)
+}
+
+// uploadState sends the last set image of the display button with the given index (1-6), or clears the display
+// button if no image is set.
+func (d *Device) uploadState(ctx context.Context, index uint8) error {
+	switch {
+	case d.jpegs[index-1] != nil:
+		return d.uploadJPEG(ctx, index, d.jpegs[index-1])
+	case d.images[index-1] != nil:
+		return d.uploadImage(ctx, index, d.images[index-1])
+	case d.displayedKnown && d.displayed[index-1] == nil:
+		return nil
+	default:
+		return d.clearDisplays(ctx, index)
+	}
+}
+
+// stateImage returns the last set image of the display button with the given index (1-6), or nil if no image
+// is set. JPEG data set with SetImageJPEG is decoded.
+func (d *Device) stateImage(index uint8) image.Image {
+	if d.images[index-1] != nil {
+		return d.images[index-1]
+	}
+	if d.jpegs[index-1] == nil {
+		return nil
+	}
+	img, err := jpeg.Decode(bytes.NewReader(d.jpegs[index-1]))
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// isConnectionError indicates if the given error was caused by a lost connection to the USB device.
+func isConnectionError(err error) bool {
+	if errors.Is(err, ErrNotConnected) {
+		return true
+	}
+	if isTransientError(err) {
+		return false
+	}
+
+	var usbErr gousb.Error
+	var transferStatus gousb.TransferStatus
+	return errors.As(err, &usbErr) || errors.As(err, &transferStatus)
+}
@@ -0,0 +1,540 @@
+package strmctrl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	remoteDescriptor    = "descriptor"
+	remoteSetBrightness = "setBrightness"
+	remoteClear         = "clear"
+	remoteSetImage      = "setImage"
+	remoteSetImages     = "setImages"
+	remoteReadEvents    = "readEvents"
+)
+
+var (
+	errRemoteDisconnected = errors.New("the connection to the remote controller was lost")
+	errRemoteClosed       = errors.New("the remote controller is closed")
+)
+
+// remoteMessage is exchanged between RemoteController and ServeRemote as newline delimited JSON.
+// Requests have an ID and a command, responses have the ID of the request and an error or a result.
+// Events have no ID.
+type remoteMessage struct {
+	ID      uint64   `json:"id,omitempty"`
+	Command string   `json:"command,omitempty"`
+	Display Control  `json:"display,omitempty"`
+	Percent uint8    `json:"percent,omitempty"`
+	Images  [][]byte `json:"images,omitempty"` // PNG encoded, empty for nil images
+	Event   *Event   `json:"event,omitempty"`
+	Result  string   `json:"result,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ServeRemote provides the given controller to a RemoteController on the other end of the given connection.
+// It handles the requests until the connection is closed or the given context is done. The connection is
+// closed when ServeRemote returns, the controller stays open.
+func ServeRemote(ctx context.Context, conn net.Conn, controller Controller) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var writeLock sync.Mutex
+	encoder := json.NewEncoder(conn)
+	send := func(msg remoteMessage) error {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		return encoder.Encode(msg)
+	}
+
+	readingEvents := false
+	decoder := json.NewDecoder(conn)
+	for {
+		var request remoteMessage
+		err := decoder.Decode(&request)
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read request: %w", err)
+		}
+
+		response := remoteMessage{ID: request.ID}
+		if request.Command == remoteReadEvents && !readingEvents {
+			err = forwardEvents(ctx, controller, send)
+			readingEvents = err == nil
+		} else if request.Command != remoteReadEvents {
+			response.Result, err = serveRemoteRequest(ctx, controller, request)
+		}
+		if err != nil {
+			response.Error = err.Error()
+		}
+
+		err = send(response)
+		if err != nil {
+			return fmt.Errorf("cannot send response: %w", err)
+		}
+	}
+}
+
+func serveRemoteRequest(ctx context.Context, controller Controller, request remoteMessage) (string, error) {
+	switch request.Command {
+	case remoteDescriptor:
+		return controller.Descriptor(), nil
+	case remoteSetBrightness:
+		return "", controller.SetBrightness(ctx, request.Percent)
+	case remoteClear:
+		return "", controller.Clear(ctx)
+	case remoteSetImage:
+		imgs, err := decodeRemoteImages(request.Images)
+		if err != nil {
+			return "", err
+		}
+		if len(imgs) != 1 {
+			return "", fmt.Errorf("expected one image, got %d", len(imgs))
+		}
+		if imgs[0] == nil {
+			return "", fmt.Errorf("%w: the image is empty", ErrInvalidImageSize)
+		}
+		return "", controller.SetImage(ctx, request.Display, imgs[0])
+	case remoteSetImages:
+		imgs, err := decodeRemoteImages(request.Images)
+		if err != nil {
+			return "", err
+		}
+		if len(imgs) != 6 {
+			return "", fmt.Errorf("expected six images, got %d", len(imgs))
+		}
+		return "", controller.SetImages(ctx, [6]image.Image(imgs))
+	default:
+		return "", fmt.Errorf("unknown command %q", request.Command)
+	}
+}
+
+// forwardEvents sends the events of the given controller until the given context is done.
+func forwardEvents(ctx context.Context, controller Controller, send func(remoteMessage) error) error {
+	events, err := controller.ReadEvents(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for e := range events {
+			err := send(remoteMessage{Event: &e})
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func encodeRemoteImages(imgs ...image.Image) ([][]byte, error) {
+	result := make([][]byte, len(imgs))
+	for i, img := range imgs {
+		if img == nil {
+			continue
+		}
+		buf := &bytes.Buffer{}
+		err := png.Encode(buf, img)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode image %d: %w", i, err)
+		}
+		result[i] = buf.Bytes()
+	}
+	return result, nil
+}
+
+func decodeRemoteImages(data [][]byte) ([]image.Image, error) {
+	result := make([]image.Image, len(data))
+	for i, pngData := range data {
+		if len(pngData) == 0 {
+			continue
+		}
+		// check the size before decoding, the data may declare huge dimensions
+		config, err := png.DecodeConfig(bytes.NewReader(pngData))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode image %d: %w", i, err)
+		}
+		if config.Width != ImageSize || config.Height != ImageSize {
+			return nil, fmt.Errorf("%w: image %d has a size of %dx%d pixels, expected %dx%d pixels", ErrInvalidImageSize, i, config.Width, config.Height, ImageSize, ImageSize)
+		}
+		img, err := png.Decode(bytes.NewReader(pngData))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode image %d: %w", i, err)
+		}
+		result[i] = img
+	}
+	return result, nil
+}
+
+// RemoteController implements the Controller interface for a controller that is provided by ServeRemote
+// on the other end of a network connection. If the connection is lost, it is dialed again with the next
+// request, and the events are requested again. Closing the RemoteController only closes the connection,
+// not the remote controller. The methods of RemoteController are safe for concurrent use.
+type RemoteController struct {
+	dial   func(context.Context) (net.Conn, error)
+	closed chan struct{}
+
+	lock     sync.Mutex
+	conn     net.Conn
+	dialing  chan struct{} // closed when the current dial is done, nil if no dial is in progress
+	encoder  *json.Encoder
+	nextID   uint64
+	pending  map[uint64]chan remoteMessage
+	reading  bool // the events were requested with ReadEvents
+	resuming bool // the events are requested again after the connection was lost
+	queue    []Event
+	wake     chan struct{}
+}
+
+// DialRemote connects to a controller that is provided by ServeRemote at the given address.
+func DialRemote(ctx context.Context, network string, address string) (*RemoteController, error) {
+	var dialer net.Dialer
+	return NewRemoteController(ctx, func(ctx context.Context) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	})
+}
+
+// NewRemoteController connects to a controller that is provided by ServeRemote, using the given dial function.
+// The dial function is also used to reconnect when the connection was lost.
+func NewRemoteController(ctx context.Context, dial func(context.Context) (net.Conn, error)) (*RemoteController, error) {
+	result := &RemoteController{
+		dial:    dial,
+		closed:  make(chan struct{}),
+		pending: make(map[uint64]chan remoteMessage),
+		wake:    make(chan struct{}, 1),
+	}
+
+	_, err := result.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Descriptor returns the descriptor of the remote controller.
+func (r *RemoteController) Descriptor() string {
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectTimeout)
+	defer cancel()
+
+	response, err := r.request(ctx, remoteMessage{Command: remoteDescriptor})
+	if err != nil {
+		return fmt.Sprintf("remote controller (%v)", err)
+	}
+	return response.Result
+}
+
+// Close the connection to the remote controller.
+func (r *RemoteController) Close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	select {
+	case <-r.closed:
+		return
+	default:
+		close(r.closed)
+	}
+
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	r.failPending()
+}
+
+func (r *RemoteController) SetBrightness(ctx context.Context, percent uint8) error {
+	_, err := r.request(ctx, remoteMessage{Command: remoteSetBrightness, Percent: percent})
+	return err
+}
+
+func (r *RemoteController) Clear(ctx context.Context) error {
+	_, err := r.request(ctx, remoteMessage{Command: remoteClear})
+	return err
+}
+
+func (r *RemoteController) SetImage(ctx context.Context, display Control, img image.Image) error {
+	data, err := encodeRemoteImages(img)
+	if err != nil {
+		return err
+	}
+	_, err = r.request(ctx, remoteMessage{Command: remoteSetImage, Display: display, Images: data})
+	return err
+}
+
+func (r *RemoteController) SetImages(ctx context.Context, imgs [6]image.Image) error {
+	data, err := encodeRemoteImages(imgs[:]...)
+	if err != nil {
+		return err
+	}
+	_, err = r.request(ctx, remoteMessage{Command: remoteSetImages, Images: data})
+	return err
+}
+
+// ReadEvents returns a channel that provides the events of the remote controller. The channel is closed
+// when the RemoteController is closed or the given context is done. It must not be called again while
+// the returned channel is open.
+func (r *RemoteController) ReadEvents(ctx context.Context) (<-chan Event, error) {
+	r.lock.Lock()
+	if r.reading {
+		r.lock.Unlock()
+		return nil, errors.New("the events are already read")
+	}
+	r.reading = true
+	r.lock.Unlock()
+
+	_, err := r.request(ctx, remoteMessage{Command: remoteReadEvents})
+	if err != nil {
+		r.lock.Lock()
+		r.reading = false
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go r.deliverEvents(ctx, events)
+
+	return events, nil
+}
+
+// deliverEvents provides the queued events on the given channel until the context is done or the
+// RemoteController is closed. The queue decouples the connection from the consumer of the events,
+// so that the consumer can send requests while handling an event.
+func (r *RemoteController) deliverEvents(ctx context.Context, events chan<- Event) {
+	defer func() {
+		r.lock.Lock()
+		r.reading = false
+		r.queue = nil
+		r.lock.Unlock()
+		close(events)
+	}()
+
+	for {
+		r.lock.Lock()
+		queue := r.queue
+		r.queue = nil
+		r.lock.Unlock()
+
+		for _, e := range queue {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			case <-r.closed:
+				return
+			}
+		}
+		if len(queue) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.closed:
+			return
+		case <-r.wake:
+		}
+	}
+}
+
+// request sends the given request to the remote controller and waits for the response.
+func (r *RemoteController) request(ctx context.Context, request remoteMessage) (remoteMessage, error) {
+	conn, err := r.connection(ctx)
+	if err != nil {
+		return remoteMessage{}, err
+	}
+
+	responses := make(chan remoteMessage, 1)
+	r.lock.Lock()
+	r.nextID++
+	request.ID = r.nextID
+	r.pending[request.ID] = responses
+	err = r.encoder.Encode(request)
+	r.lock.Unlock()
+	if err != nil {
+		r.disconnect(conn)
+		return remoteMessage{}, fmt.Errorf("cannot send request: %w", err)
+	}
+
+	select {
+	case response, ok := <-responses:
+		if !ok {
+			return remoteMessage{}, errRemoteDisconnected
+		}
+		if response.Error != "" {
+			return response, errors.New(response.Error)
+		}
+		return response, nil
+	case <-ctx.Done():
+		r.lock.Lock()
+		delete(r.pending, request.ID)
+		r.lock.Unlock()
+		return remoteMessage{}, ctx.Err()
+	case <-r.closed:
+		return remoteMessage{}, errRemoteClosed
+	}
+}
+
+// connection returns the current connection, or dials a new connection if there is none. The connection is
+// dialed without holding the lock, concurrent callers wait for the same dial.
+func (r *RemoteController) connection(ctx context.Context) (net.Conn, error) {
+	for {
+		r.lock.Lock()
+		select {
+		case <-r.closed:
+			r.lock.Unlock()
+			return nil, errRemoteClosed
+		default:
+		}
+		if r.conn != nil {
+			conn := r.conn
+			r.lock.Unlock()
+			return conn, nil
+		}
+		if r.dialing == nil {
+			break // with the lock held
+		}
+		dialing := r.dialing
+		r.lock.Unlock()
+
+		select {
+		case <-dialing:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-r.closed:
+			return nil, errRemoteClosed
+		}
+	}
+
+	dialing := make(chan struct{})
+	r.dialing = dialing
+	r.lock.Unlock()
+
+	conn, err := r.dial(ctx)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.dialing = nil
+	close(dialing)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the remote controller: %w", err)
+	}
+	select {
+	case <-r.closed:
+		conn.Close()
+		return nil, errRemoteClosed
+	default:
+	}
+	r.conn = conn
+	r.encoder = json.NewEncoder(conn)
+	go r.readMessages(conn)
+
+	return conn, nil
+}
+
+// readMessages reads the responses and events from the given connection until it is closed.
+func (r *RemoteController) readMessages(conn net.Conn) {
+	decoder := json.NewDecoder(conn)
+	for {
+		var msg remoteMessage
+		err := decoder.Decode(&msg)
+		if err != nil {
+			r.disconnect(conn)
+			return
+		}
+
+		r.lock.Lock()
+		if msg.Event != nil {
+			if r.reading {
+				r.queue = append(r.queue, *msg.Event)
+				select {
+				case r.wake <- struct{}{}:
+				default:
+				}
+			}
+		} else if responses, ok := r.pending[msg.ID]; ok {
+			delete(r.pending, msg.ID)
+			responses <- msg
+		}
+		r.lock.Unlock()
+	}
+}
+
+// disconnect closes the given connection if it is still the current connection. If the events
+// were requested, they are requested again on a new connection.
+func (r *RemoteController) disconnect(conn net.Conn) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.conn != conn {
+		return
+	}
+	conn.Close()
+	r.conn = nil
+	r.failPending()
+
+	if r.reading && !r.resuming {
+		r.resuming = true
+		go r.resumeEvents()
+	}
+}
+
+// failPending lets all pending requests fail. The lock must be held when calling failPending.
+func (r *RemoteController) failPending() {
+	for id, responses := range r.pending {
+		close(responses)
+		delete(r.pending, id)
+	}
+}
+
+// resumeEvents requests the events again after the connection was lost, until it succeeds,
+// the events are not needed anymore, or the RemoteController is closed.
+func (r *RemoteController) resumeEvents() {
+	defer func() {
+		r.lock.Lock()
+		r.resuming = false
+		r.lock.Unlock()
+	}()
+
+	for {
+		select {
+		case <-r.closed:
+			return
+		case <-time.After(reconnectInterval):
+		}
+
+		r.lock.Lock()
+		reading := r.reading
+		r.lock.Unlock()
+		if !reading {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), reconnectTimeout)
+		_, err := r.request(ctx, remoteMessage{Command: remoteReadEvents})
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+}
+
+var _ Controller = (*RemoteController)(nil)
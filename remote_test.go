@@ -0,0 +1,174 @@
+package strmctrl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestRemote provides the given simulator through ServeRemote and returns a RemoteController connected to it.
+func newTestRemote(t *testing.T, sim *Simulator) *RemoteController {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	remote, err := NewRemoteController(ctx, func(context.Context) (net.Conn, error) {
+		client, server := net.Pipe()
+		go ServeRemote(ctx, server, sim)
+		return client, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(remote.Close)
+	return remote
+}
+
+func TestRemoteSetImage(t *testing.T) {
+	sim := newTestSimulator(t)
+	remote := newTestRemote(t, sim)
+
+	err := remote.SetImage(context.Background(), DisplayTopCenter, uniformImage(color.White))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := sim.Image(DisplayTopCenter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img == nil {
+		t.Fatal("expected the image to be shown")
+	}
+	actual := color.RGBAModel.Convert(img.At(ImageSize/2, ImageSize/2)).(color.RGBA)
+	if !similarColor(actual, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("expected a white image, got %v", actual)
+	}
+}
+
+func TestRemoteSetBrightness(t *testing.T) {
+	sim := newTestSimulator(t)
+	remote := newTestRemote(t, sim)
+
+	err := remote.SetBrightness(context.Background(), 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sim.GetBrightness() != 40 {
+		t.Errorf("expected 40%%, got %d%%", sim.GetBrightness())
+	}
+}
+
+func TestRemoteReadEvents(t *testing.T) {
+	sim := newTestSimulator(t)
+	remote := newTestRemote(t, sim)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	events, err := remote.ReadEvents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Event{
+		{Control: ButtonLeft, Action: Pressed},
+		{Control: ButtonLeft, Action: Released},
+		{Control: KnobTop, Action: TurnedCCW},
+	}
+	for _, e := range expected {
+		sim.Inject(e)
+	}
+
+	for i, e := range expected {
+		select {
+		case actual := <-events:
+			if actual.Control != e.Control || actual.Action != e.Action {
+				t.Errorf("event %d: expected %v %v, got %v %v", i, e.Control, e.Action, actual.Control, actual.Action)
+			}
+		case <-ctx.Done():
+			t.Fatalf("event %d: expected %v %v, got nothing", i, e.Control, e.Action)
+		}
+	}
+}
+
+func TestRemoteSetImageRejectsEmptyImage(t *testing.T) {
+	sim := newTestSimulator(t)
+	remote := newTestRemote(t, sim)
+
+	err := remote.SetImage(context.Background(), DisplayTopLeft, nil)
+
+	if err == nil {
+		t.Error("expected an error for an empty image")
+	}
+	if sim.Err() != nil {
+		t.Errorf("expected the device to stay usable, got %v", sim.Err())
+	}
+}
+
+func TestDecodeRemoteImagesChecksSizeFirst(t *testing.T) {
+	// a large image compresses to a few bytes, it must not be decoded
+	var data bytes.Buffer
+	err := png.Encode(&data, image.NewGray(image.Rect(0, 0, 4000, 4000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = decodeRemoteImages([][]byte{data.Bytes()})
+
+	if !errors.Is(err, ErrInvalidImageSize) {
+		t.Errorf("expected ErrInvalidImageSize, got %v", err)
+	}
+}
+
+func TestRemoteCloseWhileDialing(t *testing.T) {
+	sim := newTestSimulator(t)
+	dialed := make(chan struct{})
+	blocked := make(chan struct{})
+	defer close(blocked)
+	first := true
+	remote, err := NewRemoteController(context.Background(), func(ctx context.Context) (net.Conn, error) {
+		if first {
+			first = false
+			client, server := net.Pipe()
+			go ServeRemote(ctx, server, sim)
+			return client, nil
+		}
+		close(dialed)
+		<-blocked
+		return nil, errors.New("dial failed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.lock.Lock()
+	conn := remote.conn
+	remote.lock.Unlock()
+	remote.disconnect(conn)
+
+	requestErr := make(chan error)
+	go func() {
+		requestErr <- remote.SetBrightness(context.Background(), 50)
+	}()
+	<-dialed
+
+	closed := make(chan struct{})
+	go func() {
+		remote.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close is blocked by the dial")
+	}
+	blocked <- struct{}{}
+	if err := <-requestErr; err == nil {
+		t.Error("expected the request to fail")
+	}
+}
@@ -0,0 +1,62 @@
+package strmctrl
+
+import (
+	"image"
+)
+
+// SetRotation sets the clockwise rotation in degrees of the images that are shown on the given display button,
+// overriding the rotation set with WithRotation. The rotation is rounded to a multiple of 90 degrees. The rotation
+// applies to images that are sent afterwards, use Refresh to rotate the current images. JPEG data that is set
+// with SetImageJPEG is not rotated.
+func (d *Device) SetRotation(display Control, degrees int) error {
+	if err := checkDisplay(display); err != nil {
+		return err
+	}
+	d.rotations[display-DisplayTopLeft].Store(int32(normalizeRotation(degrees)))
+	return nil
+}
+
+// Rotation returns the clockwise rotation in degrees (0, 90, 180, or 270) of the images that are shown on the
+// given display button. It returns zero if the given control is not a display.
+func (d *Device) Rotation(display Control) int {
+	if !display.IsDisplay() {
+		return 0
+	}
+	return int(d.rotations[display-DisplayTopLeft].Load())
+}
+
+// normalizeRotation rounds the given rotation in degrees to the nearest multiple of 90 degrees in the range 0-270.
+func normalizeRotation(degrees int) int {
+	degrees = ((degrees % 360) + 360) % 360
+	return (degrees + 45) / 90 * 90 % 360
+}
+
+// rotateImage rotates the given image clockwise by the given degrees (0, 90, 180, or 270).
+func rotateImage(img image.Image, degrees int) image.Image {
+	if degrees == 0 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	size := image.Pt(width, height)
+	if degrees != 180 {
+		size = image.Pt(height, width)
+	}
+	result := image.NewRGBA(image.Rectangle{Max: size})
+	for y := range height {
+		for x := range width {
+			var dx, dy int
+			switch degrees {
+			case 90:
+				dx, dy = height-1-y, x
+			case 180:
+				dx, dy = width-1-x, height-1-y
+			case 270:
+				dx, dy = y, width-1-x
+			}
+			result.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return result
+}
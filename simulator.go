@@ -0,0 +1,232 @@
+package strmctrl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"sync"
+	"time"
+)
+
+const (
+	simulatorSerial       = "SIMULATOR"
+	simulatorProduct      = "Stream Controller SE Simulator"
+	simulatorRelease      = "1.00"
+	simulatorPacketSize   = 512
+	simulatorPollInterval = 1 * time.Millisecond
+)
+
+// Simulator is an in-memory replacement for a Stream Controller SE device. It provides the same
+// methods as Device, records the brightness and images that were sent to the simulated device, and
+// allows to inject events. The Simulator is useful to develop and test applications without the hardware.
+type Simulator struct {
+	*Device
+	transport *simTransport
+}
+
+// NewSimulator returns a new Simulator. The given options are applied to the simulated device.
+func NewSimulator(opts ...Option) (*Simulator, error) {
+	transport := newSimTransport()
+	device, err := OpenTransport(transport, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Simulator{
+		Device:    device,
+		transport: transport,
+	}, nil
+}
+
+// Inject the given event as if it was sent by the simulated device. Only events that can be sent
+// by the hardware (press, release, and rotation of the controls) can be injected.
+func (s *Simulator) Inject(e Event) error {
+	control, state, err := encodeEvent(e)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, eventFrameSize)
+	frame[9] = byte(control)
+	frame[10] = state
+
+	return s.transport.inject(frame)
+}
+
+// RawBrightness returns the raw brightness value that was last sent to the simulated device. This is the
+// value after the mapping of WithBrightnessGamma, e.g. 22 after SetBrightness(50) with the default gamma.
+// Use GetBrightness for the brightness in percent.
+func (s *Simulator) RawBrightness() uint8 {
+	s.transport.lock.Lock()
+	defer s.transport.lock.Unlock()
+
+	return s.transport.brightness
+}
+
+// Image returns the image that was last sent to the given display button of the simulated device,
+// or nil if the display button is blank.
+func (s *Simulator) Image(display Control) (image.Image, error) {
+	if err := checkDisplay(display); err != nil {
+		return nil, err
+	}
+
+	s.transport.lock.Lock()
+	jpg := s.transport.images[display-1]
+	s.transport.lock.Unlock()
+
+	if jpg == nil {
+		return nil, nil
+	}
+	return jpeg.Decode(bytes.NewReader(jpg))
+}
+
+// simTransport implements the device's side of the CRT protocol in memory.
+type simTransport struct {
+	lock       sync.Mutex
+	brightness uint8
+	images     [6][]byte
+
+	// the image that is currently uploaded with a BAT command
+	uploadIndex uint8
+	uploadSize  int
+	upload      []byte
+
+	frames chan []byte
+	closed chan struct{}
+}
+
+func newSimTransport() *simTransport {
+	return &simTransport{
+		frames: make(chan []byte, 100),
+		closed: make(chan struct{}),
+	}
+}
+
+func (t *simTransport) inject(frame []byte) error {
+	select {
+	case <-t.closed:
+		return ErrNotConnected
+	default:
+	}
+
+	select {
+	case t.frames <- frame:
+		return nil
+	default:
+		return errors.New("too many events are pending")
+	}
+}
+
+func (t *simTransport) Info() DeviceInfo {
+	return DeviceInfo{Serial: simulatorSerial, Product: simulatorProduct, Release: simulatorRelease}
+}
+
+func (t *simTransport) InEndpoint() EndpointDesc {
+	return EndpointDesc{
+		MaxPacketSize: simulatorPacketSize,
+		PollInterval:  simulatorPollInterval,
+	}
+}
+
+func (t *simTransport) OutEndpoint() EndpointDesc {
+	return EndpointDesc{
+		MaxPacketSize: simulatorPacketSize,
+	}
+}
+
+func (t *simTransport) Read(ctx context.Context, packet []byte) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-t.closed:
+		return 0, ErrNotConnected
+	case frame := <-t.frames:
+		return copy(packet, frame), nil
+	}
+}
+
+func (t *simTransport) Write(ctx context.Context, packet []byte) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-t.closed:
+		return 0, ErrNotConnected
+	default:
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.upload != nil {
+		t.receiveImageData(packet)
+		return len(packet), nil
+	}
+
+	cmd, args, err := parseCRTCommand(packet)
+	if err != nil {
+		return 0, err
+	}
+	switch cmd {
+	case "LIG":
+		t.brightness = args[0]
+	case "CLE":
+		if args[1] == allDisplays {
+			clear(t.images[:])
+		} else if args[1] >= 1 && args[1] <= 6 {
+			t.images[args[1]-1] = nil
+		}
+	case "BAT":
+		t.uploadSize = int(args[0])<<8 | int(args[1])
+		t.uploadIndex = args[2]
+		t.upload = make([]byte, 0, t.uploadSize)
+	}
+
+	return len(packet), nil
+}
+
+func (t *simTransport) receiveImageData(packet []byte) {
+	n := min(len(packet), t.uploadSize-len(t.upload))
+	t.upload = append(t.upload, packet[:n]...)
+	if len(t.upload) < t.uploadSize {
+		return
+	}
+
+	if t.uploadIndex >= 1 && t.uploadIndex <= 6 {
+		t.images[t.uploadIndex-1] = t.upload
+	}
+	t.upload = nil
+}
+
+func (t *simTransport) Close() {
+	select {
+	case <-t.closed:
+	default:
+		close(t.closed)
+	}
+}
+
+// parseCRTCommand splits a packet that was built by sendCRTCommand into the command and its arguments.
+// The returned arguments are padded with zeros, so that the known commands can access their arguments safely.
+func parseCRTCommand(packet []byte) (string, []byte, error) {
+	const prefix = "CRT\x00\x00"
+	if !bytes.HasPrefix(packet, []byte(prefix)) {
+		return "", nil, fmt.Errorf("invalid CRT command: % x", packet)
+	}
+
+	rest := packet[len(prefix):]
+	end := bytes.IndexByte(rest, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("invalid CRT command: % x", packet)
+	}
+	cmd := string(rest[:end])
+
+	args := make([]byte, 3)
+	if end+2 < len(rest) {
+		copy(args, rest[end+2:])
+	}
+
+	return cmd, args, nil
+}
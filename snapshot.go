@@ -0,0 +1,39 @@
+package strmctrl
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Snapshot returns an image of the current contents of all display buttons, laid out in their grid with
+// the physical gaps between them (see WithDisplayGap). The device cannot read back its displays,
+// therefore the snapshot is composed from the images that were last set. Display buttons that were not set
+// or were cleared are black, like the gaps.
+func (d *Device) Snapshot() image.Image {
+	var images [6]image.Image
+	d.lock.Lock()
+	for i := range images {
+		images[i] = d.stateImage(uint8(i + 1))
+	}
+	d.lock.Unlock()
+
+	gap := d.settings.displayGap
+	width := GridColumns*ImageSize + (GridColumns-1)*gap
+	height := GridRows*ImageSize + (GridRows-1)*gap
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(result, result.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	for i, img := range images {
+		if img == nil {
+			continue
+		}
+
+		col, row := i%GridColumns, i/GridColumns
+		origin := image.Pt(col*(ImageSize+gap), row*(ImageSize+gap))
+		tile := image.Rect(0, 0, ImageSize, ImageSize).Add(origin)
+		draw.Draw(result, tile, img, img.Bounds().Min, draw.Src)
+	}
+
+	return result
+}
@@ -3,10 +3,14 @@ package strmctrl
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"image"
-	"image/jpeg"
-	"log"
+	"image/color"
+	"image/draw"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/gousb"
@@ -15,53 +19,82 @@ import (
 const (
 	// ImageSize is the width and height of the quadratic images for the display buttons in pixels.
 	ImageSize = 64
+
+	// MaxImageBytes is the maximum size of an encoded image that can be sent to the device.
+	MaxImageBytes = 0xffff
+
+	// RotationIdleGap is the pause after which the velocity of a knob's rotation is reset.
+	RotationIdleGap = 250 * time.Millisecond
 )
 
 const (
-	vid = gousb.ID(0x1500)
-	pid = gousb.ID(0x3001)
+	// DefaultVendorID is the USB vendor ID of the Stream Controller SE.
+	DefaultVendorID gousb.ID = 0x1500
+	// DefaultProductID is the USB product ID of the Stream Controller SE.
+	DefaultProductID gousb.ID = 0x3001
+)
 
-	commandTimeout = 100 * time.Millisecond
+const (
+	commandTimeout    = 100 * time.Millisecond
+	writeRetryBackoff = 10 * time.Millisecond
+	// fallbackPollInterval paces the reads if the IN endpoint reports no poll interval, e.g. a bulk endpoint.
+	fallbackPollInterval = time.Millisecond
+
+	allDisplays uint8 = 0xff
+
+	// eventFrameSize is the minimum number of bytes of a frame that contains an input event.
+	eventFrameSize = 11
 )
 
 type DeviceInfo struct {
 	Bus     int
 	Address int
 	Serial  string
+	Product string // the product name from the USB descriptor, empty if it cannot be read
+	Release string // the release number of the device (bcdDevice) from the USB descriptor
 }
 
 func (i DeviceInfo) String() string {
 	return fmt.Sprintf("Bus %03d Device %03d: Serial %s", i.Bus, i.Address, i.Serial)
 }
 
-// List the available Stream Controller SE devices with their serial number.
-func List() ([]DeviceInfo, error) {
-	usb := gousb.NewContext()
-	defer usb.Close()
+// List the available Stream Controller SE devices with their serial number. Only the options WithUSBContext
+// and WithUSBID are used by List, all other options are ignored. Devices that cannot be read completely are still listed,
+// e.g. with an empty serial number. In this case, the returned error describes the problems, together with
+// the information of all devices that were found.
+func List(opts ...Option) ([]DeviceInfo, error) {
+	settings := newSettings(opts)
+	usb := settings.usb
+	if usb == nil {
+		usb = gousb.NewContext()
+		defer usb.Close()
+	}
 
+	return listDevices(usb, settings.usbID)
+}
+
+func listDevices(usb *gousb.Context, id usbID) ([]DeviceInfo, error) {
 	// OpenDevices is used to find the devices to open.
-	devices, err := usb.OpenDevices(func(desc *gousb.DeviceDesc) bool {
-		return desc.Vendor == vid && desc.Product == pid
-	})
+	devices, err := usb.OpenDevices(id.matches)
+	var errs []error
 	if err != nil {
-		return nil, fmt.Errorf("cannot enumerate devices: %w", err)
+		errs = append(errs, fmt.Errorf("cannot enumerate all devices: %w", err))
 	}
 
-	result := make([]DeviceInfo, len(devices))
-	for i, device := range devices {
-		serial, err := device.SerialNumber()
-		if err != nil {
-			return nil, fmt.Errorf("cannot read serial number from device %d: %w", i, err)
+	result := make([]DeviceInfo, 0, len(devices))
+	for _, device := range devices {
+		if device == nil {
+			continue
 		}
-		device.Close()
-		result[i] = DeviceInfo{
-			Bus:     device.Desc.Bus,
-			Address: device.Desc.Address,
-			Serial:  serial,
+		info := deviceInfo(device)
+		if _, err := device.SerialNumber(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot read serial number from device on bus %03d with address %03d: %w", info.Bus, info.Address, err))
 		}
+		device.Close()
+		result = append(result, info)
 	}
 
-	return result, nil
+	return result, errors.Join(errs...)
 }
 
 type Control uint8
@@ -76,11 +109,42 @@ const (
 	ButtonLeft
 	ButtonCenter
 	ButtonRight
+	// All knobs can be turned and pressed, also at the same time, see Event.WhilePressed.
 	KnobTop
 	KnobBottomLeft
 	KnobBottomRight
 )
 
+var controlNames = map[Control]string{
+	DisplayTopLeft:      "DisplayTopLeft",
+	DisplayTopCenter:    "DisplayTopCenter",
+	DisplayTopRight:     "DisplayTopRight",
+	DisplayBottomLeft:   "DisplayBottomLeft",
+	DisplayBottomCenter: "DisplayBottomCenter",
+	DisplayBottomRight:  "DisplayBottomRight",
+	ButtonLeft:          "ButtonLeft",
+	ButtonCenter:        "ButtonCenter",
+	ButtonRight:         "ButtonRight",
+	KnobTop:             "KnobTop",
+	KnobBottomLeft:      "KnobBottomLeft",
+	KnobBottomRight:     "KnobBottomRight",
+}
+
+func (c Control) String() string {
+	if name, ok := controlNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Control(%d)", uint8(c))
+}
+
+// seControls are the controls of the Stream Controller SE.
+var seControls = []Control{
+	DisplayTopLeft, DisplayTopCenter, DisplayTopRight,
+	DisplayBottomLeft, DisplayBottomCenter, DisplayBottomRight,
+	ButtonLeft, ButtonCenter, ButtonRight,
+	KnobTop, KnobBottomLeft, KnobBottomRight,
+}
+
 func (c Control) IsDisplay() bool {
 	return c >= DisplayTopLeft && c <= DisplayBottomRight
 }
@@ -100,33 +164,82 @@ const (
 	Pressed
 	TurnedCW
 	TurnedCCW
+	LongPressed   // synthesized, see WithLongPress
+	DoublePressed // synthesized, see WithDoublePress
 )
 
+var actionNames = map[Action]string{
+	Released:      "Released",
+	Pressed:       "Pressed",
+	TurnedCW:      "TurnedCW",
+	TurnedCCW:     "TurnedCCW",
+	LongPressed:   "LongPressed",
+	DoublePressed: "DoublePressed",
+}
+
+func (a Action) String() string {
+	if name, ok := actionNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("Action(%d)", uint8(a))
+}
+
+// IsPress indicates if the action is a press-type action, i.e. Pressed or Released. Use IsDown
+// to check if the control is held down.
 func (a Action) IsPress() bool {
-	return a >= Released && a <= Pressed
+	return a == Released || a == Pressed
+}
+
+// IsDown indicates if the action means that the control is held down, i.e. Pressed.
+func (a Action) IsDown() bool {
+	return a == Pressed
 }
 
 func (a Action) IsRotation() bool {
-	return a >= TurnedCW && a <= TurnedCCW
+	return a == TurnedCW || a == TurnedCCW
 }
 
 type Event struct {
 	Control Control
 	Action  Action
+	Time    time.Time // the point in time when the event was received
+	Raw     []byte    // the received frame, only if enabled with WithRawEvents
+
+	// Velocity is the speed of a rotation in steps per second, derived from the time since the previous
+	// rotation of the same knob in the same direction. It is zero for the first rotation after a pause
+	// (see RotationIdleGap) or a change of direction, and for all other actions.
+	Velocity float64
+	// Steps is the number of steps of a rotation, positive for clockwise and negative for counterclockwise
+	// rotations. It is 1 or -1, unless rotations are coalesced with WithRotationCoalescing.
+	Steps int
+	// WhilePressed indicates that the knob was pressed during the rotation. This can be used as modifier,
+	// e.g. for fine adjustments. All three knobs can be pressed.
+	WhilePressed bool
 }
 
 func (e Event) Is(control Control, action Action) bool {
 	return e.Control == control && e.Action == action
 }
 
+// IsPress indicates if the event is a press-type event (Pressed or Released) of the given control.
 func (e Event) IsPress(control Control) bool {
 	return e.Control == control && e.Action.IsPress()
 }
 
+// IsDown indicates if the event means that the given control is held down.
+func (e Event) IsDown(control Control) bool {
+	return e.Control == control && e.Action.IsDown()
+}
+
 func (e Event) IsRotation(control Control) bool {
 	return e.Control == control && e.Action.IsRotation()
 }
 
+// Since returns the time elapsed between the other event and this event.
+func (e Event) Since(other Event) time.Duration {
+	return e.Time.Sub(other.Time)
+}
+
 type hwControl uint8
 
 const (
@@ -154,154 +267,288 @@ const (
 )
 
 // Device represents one Stream Controller SE that is connected via USB.
+// The methods of Device are safe for concurrent use.
 type Device struct {
-	usb    *gousb.Context
-	device *gousb.Device
+	// dial opens a new transport to reconnect to the device, nil if reconnecting is not supported
+	dial func(context.Context) (Transport, error)
 
-	closed chan struct{}
+	closed   chan struct{}
+	settings settings
 
-	config  *gousb.Config
-	intf0   *gousb.Interface
-	epIn    *gousb.InEndpoint
-	epOut   *gousb.OutEndpoint
-	lastOut time.Time
-}
+	// the rotation of the images of the display buttons in degrees, see SetRotation
+	rotations [6]atomic.Int32
 
-// Open the Stream Controller SE device with the given serial number. If the serial number
-// is empty, the first available device is opened.
-func Open(serial string) (*Device, error) {
-	usb := gousb.NewContext()
+	// keepAliveReset restarts the interval of the keep-alive pings
+	keepAliveReset chan struct{}
 
-	devices, err := usb.OpenDevices(func(desc *gousb.DeviceDesc) bool {
-		return desc.Vendor == vid && desc.Product == pid
-	})
-	if err != nil {
-		for _, device := range devices {
-			if device != nil {
-				device.Close()
-			}
-		}
-		usb.Close()
-		return nil, fmt.Errorf("cannot find device: %w", err)
-	}
+	// ops is cancelled by Close to abort the commands that are in flight
+	ops       context.Context
+	cancelOps context.CancelFunc
 
-	var foundDevice *gousb.Device
-	for _, device := range devices {
-		if foundDevice != nil {
-			device.Close()
-			continue
-		}
+	errLock sync.Mutex
+	err     error
 
-		if serial == "" {
-			foundDevice = device
-			continue
-		}
+	rawLock   sync.Mutex
+	rawFrames map[chan []byte]struct{} // the subscribers of RawFrames
 
-		deviceSerial, err := device.SerialNumber()
-		if err != nil {
-			device.Close()
-			continue
-		}
+	// lock serializes the communication with the device and protects the connection and the state below
+	lock sync.Mutex
 
-		if serial == deviceSerial {
-			foundDevice = device
-			continue
-		}
+	brightness    uint8
+	brightnessSet bool
+	onBrightness  uint8 // the last brightness above zero, restored by ToggleBrightness
+	images        [6]image.Image
+	jpegs         [6][]byte // the JPEG data of the display buttons that were set with SetImageJPEG
 
-		device.Close()
-	}
+	lastActivity time.Time // the point in time of the last event or change of the brightness
+	dimmed       bool      // the display is dimmed because of inactivity
 
-	if foundDevice == nil {
-		usb.Close()
-		return nil, fmt.Errorf("cannot find device %s", serial)
-	}
+	// the JPEG data that is currently displayed, only valid if displayedKnown is true
+	displayed      [6][]byte
+	displayedKnown bool
+	updated        [6]time.Time // the point in time when the display buttons were last written
+	throttled      [6]bool      // a delayed update of the display button is scheduled, see WithMaxFPS
 
-	err = foundDevice.SetAutoDetach(true)
-	if err != nil {
-		foundDevice.Close()
-		usb.Close()
-		return nil, fmt.Errorf("cannot set autoDetach: %w", err)
-	}
-	err = foundDevice.Reset()
+	generation int  // incremented with every new connection
+	responsive bool // the device answered the last ping
+	info       DeviceInfo
+	transport  Transport
+	lastOut    time.Time
+	packets    sync.Pool
+
+	// the disconnect handler was already called for the current connection
+	disconnectNotified bool
+}
+
+// Open the Stream Controller SE device with the given serial number. If the serial number
+// is empty, the first available device is opened. The given options are applied to the device.
+func Open(serial string, opts ...Option) (*Device, error) {
+	return OpenContext(context.Background(), serial, opts...)
+}
+
+// OpenContext opens the Stream Controller SE device with the given serial number like Open.
+// If the given context is done before the device is completely opened, all resources are
+// released and the context's error is returned.
+func OpenContext(ctx context.Context, serial string, opts ...Option) (*Device, error) {
+	return open(ctx, nil, selectSerial(serial), opts)
+}
+
+// OpenByAddress opens the Stream Controller SE device that is connected to the given USB bus
+// with the given address. The given options are applied to the device.
+func OpenByAddress(bus int, address int, opts ...Option) (*Device, error) {
+	return open(context.Background(), nil, selectAddress(bus, address), opts)
+}
+
+// OpenTransport opens a device that communicates through the given transport. The given options
+// are applied to the device. The transport is closed when the device is closed. A device that was
+// opened with OpenTransport cannot be reconnected automatically.
+func OpenTransport(transport Transport, opts ...Option) (*Device, error) {
+	result := newDevice(opts)
+
+	err := result.connect(context.Background(), func(context.Context) (Transport, error) {
+		return transport, nil
+	})
 	if err != nil {
-		foundDevice.Close()
-		usb.Close()
-		return nil, fmt.Errorf("cannot reset device: %v", err)
+		result.cancelOps()
+		return nil, err
 	}
 
-	result := &Device{
-		usb:    usb,
-		device: foundDevice,
-		closed: make(chan struct{}),
+	go result.keepAlive()
+	go result.idleDim()
+
+	return result, nil
+}
+
+// open the device chosen by the given selector. If the given USB context is nil, the context set with
+// WithUSBContext is used, or the device uses its own USB context.
+func open(ctx context.Context, usb *gousb.Context, selector deviceSelector, opts []Option) (*Device, error) {
+	result := newDevice(opts)
+	if usb == nil {
+		usb = result.settings.usb
 	}
+	filter := result.settings.deviceFilter
+	id := result.settings.usbID
+	reset := result.settings.usbReset
 
-	err = result.setupEndpoints()
+	err := result.connect(ctx, func(ctx context.Context) (Transport, error) {
+		return openUSBTransport(ctx, usb, id, selector.filtered(filter), reset)
+	})
 	if err != nil {
-		result.Close()
-		return nil, fmt.Errorf("cannot setup endpoints: %w", err)
+		result.cancelOps()
+		return nil, err
 	}
 
-	err = result.init()
-	if err != nil {
-		result.Close()
-		return nil, fmt.Errorf("cannot initialize device: %w", err)
+	// use the serial number to reconnect to the same device
+	serial := result.info.Serial
+	result.dial = func(ctx context.Context) (Transport, error) {
+		return openUSBTransport(ctx, usb, id, selectSerial(serial).filtered(filter), reset)
 	}
 
 	go result.keepAlive()
+	go result.idleDim()
 
 	return result, nil
 }
 
-func (d *Device) setupEndpoints() error {
-	var err error
+func newDevice(opts []Option) *Device {
+	ops, cancelOps := context.WithCancel(context.Background())
+	result := &Device{
+		closed:         make(chan struct{}),
+		settings:       newSettings(opts),
+		keepAliveReset: make(chan struct{}, 1),
+		ops:            ops,
+		cancelOps:      cancelOps,
+		lastActivity:   time.Now(),
+	}
+	for i := range result.rotations {
+		result.rotations[i].Store(int32(result.settings.rotation))
+	}
+	return result
+}
 
-	d.config, err = d.device.Config(1)
+// connect opens a new transport using the given dial function and initializes the device.
+func (d *Device) connect(ctx context.Context, dial func(context.Context) (Transport, error)) error {
+	var err error
+	d.transport, err = dial(ctx)
 	if err != nil {
-		return fmt.Errorf("cannot open config: %w", err)
+		return err
 	}
+	d.info = d.transport.Info()
+	d.generation++
+	d.invalidateDisplayed()
 
-	d.intf0, err = d.config.Interface(0, 0)
-	if err != nil {
-		return fmt.Errorf("cannot get interface: %w", err)
+	if ctx.Err() != nil {
+		d.disconnect()
+		return ctx.Err()
 	}
 
-	d.epIn, err = d.intf0.InEndpoint(2)
+	err = d.init(ctx)
 	if err != nil {
-		return fmt.Errorf("cannot create IN endpoint: %w", err)
+		d.disconnect()
+		return fmt.Errorf("cannot initialize device: %w", err)
 	}
-
-	d.epOut, err = d.intf0.OutEndpoint(3)
+	err = d.settle(ctx)
 	if err != nil {
-		return fmt.Errorf("cannot create OUT endpoint: %w", err)
+		d.disconnect()
+		return fmt.Errorf("device is not ready: %w", err)
 	}
+	d.responsive = true
+	d.disconnectNotified = false
 
 	return nil
 }
 
-func (d *Device) init() error {
-	err := d.sendCRTCommandWithTimeout("DIS")
+func (d *Device) init(ctx context.Context) error {
+	err := d.sendCRTCommandWithTimeout(ctx, "DIS")
 	if err != nil {
 		return err
 	}
-	return d.sendCRTCommandWithTimeout("CONNECT")
+	return d.sendCRTCommandWithTimeout(ctx, "CONNECT")
+}
+
+// settle waits for the configured settle time after the handshake and writes a second CONNECT command, so that
+// the firmware is ready to accept images when the device is used for the first time. Nothing is read from the
+// device, only a failing write is reported.
+func (d *Device) settle(ctx context.Context) error {
+	if d.settings.settleTime <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d.settings.settleTime)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+
+	return d.sendCRTCommandWithTimeout(ctx, "CONNECT")
 }
 
+// keepAlive pings the device periodically until the device is closed. The pings are derived from the
+// device's context, so that a ping in flight is aborted when the device is closed.
 func (d *Device) keepAlive() {
-	tick := time.NewTicker(5 * time.Second)
+	if d.settings.keepAliveInterval <= 0 {
+		return
+	}
+	tick := time.NewTicker(d.settings.keepAliveInterval)
 	defer tick.Stop()
 
 	for {
 		select {
+		case <-d.ops.Done():
+			return
 		case <-d.closed:
 			return
+		case <-d.keepAliveReset:
+			tick.Reset(d.settings.keepAliveInterval)
 		case <-tick.C:
-			d.sendCRTCommandWithTimeout("CONNECT")
+			d.lock.Lock()
+			if d.ops.Err() == nil {
+				d.recoverFrom(d.ping(d.ops))
+			}
+			d.lock.Unlock()
 		}
 	}
 }
 
+// Reconnect repeats the initialization handshake with the device on the existing USB connection and restarts
+// the interval of the keep-alive pings. This is lighter than reopening the USB device and often enough to
+// recover a device whose firmware got confused, e.g. if it stopped to react to commands while the USB
+// connection is still fine. If the handshake fails because the connection was lost, the device is reopened
+// like with WithAutoReconnect, if enabled. The images of the display buttons are not sent again, use Refresh
+// for that. Reconnect may be called while ReadEvents is active.
+func (d *Device) Reconnect(ctx context.Context) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	err := d.init(ctx)
+	if err != nil {
+		return d.recoverFrom(fmt.Errorf("cannot repeat the handshake: %w", err))
+	}
+	d.responsive = true
+
+	select {
+	case d.keepAliveReset <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Ping checks if the device is responsive by sending a CONNECT command with a short timeout.
+// If auto-reconnect is enabled and the connection was lost, the device is reconnected.
+func (d *Device) Ping(ctx context.Context) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.recoverFrom(d.ping(ctx))
+}
+
+// IsConnected indicates if the device is connected and answered the last ping. The device
+// is pinged periodically in the background (see WithKeepAliveInterval), use Ping to check
+// the connection immediately.
+func (d *Device) IsConnected() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.transport != nil && d.responsive
+}
+
+// ping sends a CONNECT command and remembers if the device answered. The lock must be held when calling ping.
+func (d *Device) ping(ctx context.Context) error {
+	err := d.sendCRTCommandWithTimeout(ctx, "CONNECT")
+	d.responsive = err == nil
+	return err
+}
+
 // Close the device and clean up the used system resources.
 func (d *Device) Close() {
+	// abort the commands in flight, so that the lock is released promptly
+	d.cancelOps()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
 	select {
 	case <-d.closed:
 		return
@@ -309,61 +556,311 @@ func (d *Device) Close() {
 		close(d.closed)
 	}
 
-	d.sendCRTCommandWithTimeout("CLE", 0x00, 0xff)
-	d.sendCRTCommandWithTimeout("STP")
-
-	if d.intf0 != nil {
-		d.intf0.Close()
+	if d.transport != nil {
+		d.sendCRTCommandWithTimeout(context.Background(), "CLE", 0x00, allDisplays)
+		d.sendCRTCommandWithTimeout(context.Background(), "STP")
 	}
-	if d.config != nil {
-		d.config.Close()
+
+	d.disconnect()
+}
+
+// CloseOnContext closes the device when the given context is done. It returns immediately. Calling Close
+// explicitly is still safe, the device is closed only once.
+func (d *Device) CloseOnContext(ctx context.Context) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			d.Close()
+		case <-d.closed:
+		}
+	}()
+}
+
+// disconnect closes the transport of the device.
+func (d *Device) disconnect() {
+	if d.transport != nil {
+		d.transport.Close()
 	}
-	if d.device != nil {
-		d.device.Close()
+	d.transport = nil
+	d.responsive = false
+}
+
+func (d *Device) Descriptor() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return fmt.Sprintf("Bus %03d Device %03d Serial: %s", d.info.Bus, d.info.Address, d.info.Serial)
+}
+
+// Controls returns the controls that are supported by the device. Currently, all supported devices have the
+// same controls as the Stream Controller SE, but other hardware revisions may provide fewer controls.
+func (d *Device) Controls() []Control {
+	return slices.Clone(seControls)
+}
+
+// PollInterval returns the poll interval of the IN endpoint that provides the events.
+// It returns zero if the device is not connected.
+func (d *Device) PollInterval() time.Duration {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.transport == nil {
+		return 0
 	}
-	if d.usb != nil {
-		d.usb.Close()
+	return d.transport.InEndpoint().PollInterval
+}
+
+// MaxPacketSize returns the maximum packet size of the IN endpoint that provides the events.
+// It returns zero if the device is not connected.
+func (d *Device) MaxPacketSize() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.transport == nil {
+		return 0
 	}
+	return d.transport.InEndpoint().MaxPacketSize
 }
 
-func (d *Device) Descriptor() string {
-	serial, _ := d.device.SerialNumber()
-	return fmt.Sprintf("Bus %03d Device %03d Serial: %s", d.device.Desc.Bus, d.device.Desc.Address, serial)
+// FirmwareVersion returns the version of the device's firmware. The device does not provide a command to
+// query the firmware version, therefore the release number (bcdDevice) from the USB descriptor is used.
+func (d *Device) FirmwareVersion() (string, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.transport == nil {
+		return "", ErrNotConnected
+	}
+	if d.info.Release == "" {
+		return "", errors.New("the firmware version is not available")
+	}
+	return d.info.Release, nil
 }
 
 // ReadEvents returns a channel that provides the incoming events.
-// This function starts a goroutine and must only be called once.
+// This function starts a goroutine, it must not be called again while the returned channel is open.
+// The channel is closed when the device is closed, the given context is done, or reading
+// from the device failed permanently. In the latter case, Err returns the reason. If the device
+// is already closed, ReadEvents returns ErrClosed.
 func (d *Device) ReadEvents(ctx context.Context) (<-chan Event, error) {
-	events := make(chan Event)
+	if d.isClosed() {
+		return nil, ErrClosed
+	}
+	events := d.readEvents(ctx)
+	for _, stage := range d.settings.eventStages() {
+		events = stage(ctx, events)
+	}
+
+	return events, nil
+}
+
+// readEvents starts the goroutine that reads and decodes the events from the IN endpoint.
+func (d *Device) readEvents(ctx context.Context) <-chan Event {
+	events := make(chan Event, d.settings.eventBuffer)
 
 	go func() {
 		defer close(events)
 
-		buf := make([]byte, d.epIn.Desc.MaxPacketSize)
-		tick := time.NewTicker(d.epIn.Desc.PollInterval)
-		defer tick.Stop()
+		d.lock.Lock()
+		transport := d.transport
+		d.lock.Unlock()
+		if transport == nil {
+			if !d.isClosed() {
+				d.setErr(ErrNotConnected)
+			}
+			return
+		}
+		inEndpoint := transport.InEndpoint()
+		buf := make([]byte, inEndpoint.MaxPacketSize)
+		var pace <-chan time.Time
+		if d.settings.blockingReads {
+			// read again as soon as the previous read returned
+			ready := make(chan time.Time)
+			close(ready)
+			pace = ready
+		} else {
+			interval := inEndpoint.PollInterval
+			if interval <= 0 {
+				interval = fallbackPollInterval
+			}
+			tick := time.NewTicker(interval)
+			defer tick.Stop()
+			pace = tick.C
+		}
+		lastRotations := make(map[Control]Event)
+		pressedKnobs := make(map[Control]bool)
 		for {
 			select {
 			case <-d.closed:
 				return
-			case <-tick.C:
-				n, err := d.epIn.ReadContext(ctx, buf)
+			case <-ctx.Done():
+				return
+			case <-pace:
+				n, generation, err := d.readFrame(ctx, buf)
 				if err != nil {
-					continue
+					if ctx.Err() != nil || d.isClosed() || isTransientError(err) {
+						continue
+					}
+					err = d.recoverFromReadError(err, generation)
+					if err == nil {
+						continue
+					}
+					if d.settings.autoReconnect && isConnectionError(err) {
+						// wait and try again to reconnect
+						select {
+						case <-d.closed:
+						case <-ctx.Done():
+						case <-time.After(reconnectInterval):
+						}
+						continue
+					}
+					err = fmt.Errorf("cannot read from IN2 endpoint: %w", err)
+					d.lock.Lock()
+					d.notifyDisconnect(err)
+					d.lock.Unlock()
+					d.setErr(err)
+					return
 				}
 
-				if n < 11 {
-					log.Printf("received insufficient data from IN2 endpoint: %d", n)
+				if n == 0 { // nothing to read
+					continue
+				}
+				d.publishRawFrame(buf[:n])
+				if n < eventFrameSize {
+					d.settings.logger.Printf("received insufficient data from IN2 endpoint: %d", n)
+					continue
 				}
 				event, err := newEvent(hwControl(buf[9]), buf[10])
-				if err == nil { // ignore faulty events
-					events <- event
+				if err != nil { // ignore faulty events
+					if d.settings.rawEvents {
+						d.settings.logger.Printf("%v, frame: % x", err, buf[:n])
+					}
+					continue
+				}
+				event.Time = time.Now()
+				if d.settings.idleDimAfter > 0 {
+					d.wakeUp()
+				}
+				if event.Control.IsKnob() && event.Action.IsPress() {
+					pressedKnobs[event.Control] = event.Action == Pressed
+				}
+				if event.Action.IsRotation() {
+					event.Velocity = rotationVelocity(lastRotations[event.Control], event)
+					event.WhilePressed = pressedKnobs[event.Control]
+					lastRotations[event.Control] = event
+				}
+				if d.settings.rawEvents {
+					event.Raw = bytes.Clone(buf[:n])
+				}
+				select {
+				case events <- event:
+				case <-d.closed:
+					return
+				case <-ctx.Done():
+					return
 				}
 			}
 		}
 	}()
 
-	return events, nil
+	return events
+}
+
+// rotationVelocity calculates the velocity of the given rotation event in steps per second,
+// based on the previous rotation event of the same knob.
+func rotationVelocity(previous Event, e Event) float64 {
+	if previous.Action != e.Action {
+		return 0
+	}
+	elapsed := e.Since(previous)
+	if elapsed <= 0 || elapsed > RotationIdleGap {
+		return 0
+	}
+	return float64(time.Second) / float64(elapsed)
+}
+
+// readFrame reads one frame from the IN endpoint. It also returns the generation of the connection that was used.
+// The lock is not held while reading, so that reading does not block sending commands to the device.
+func (d *Device) readFrame(ctx context.Context, buf []byte) (int, int, error) {
+	d.lock.Lock()
+	transport := d.transport
+	generation := d.generation
+	d.lock.Unlock()
+
+	if transport == nil {
+		return 0, generation, ErrNotConnected
+	}
+	n, err := transport.Read(ctx, buf)
+	return n, generation, err
+}
+
+// recoverFromReadError tries to reconnect the device after reading from the connection with the
+// given generation failed. If the device was already reconnected in the meantime, nil is returned.
+func (d *Device) recoverFromReadError(err error, generation int) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if generation != d.generation {
+		return nil
+	}
+	return d.recoverFrom(err)
+}
+
+// Err returns the error that caused the events channel provided by ReadEvents to be closed.
+// It returns nil if the channel was closed regularly, because the device was closed or the
+// context was done.
+func (d *Device) Err() error {
+	d.errLock.Lock()
+	defer d.errLock.Unlock()
+	return d.err
+}
+
+func (d *Device) setErr(err error) {
+	d.errLock.Lock()
+	defer d.errLock.Unlock()
+	d.err = err
+}
+
+// aborted indicates that Close was called and aborts the commands in flight. The final commands that Close
+// sends itself are not aborted.
+func (d *Device) aborted() bool {
+	return d.ops.Err() != nil && !d.isClosed()
+}
+
+func (d *Device) isClosed() bool {
+	select {
+	case <-d.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTransientError indicates if the given error is only temporary and the operation may be repeated.
+func isTransientError(err error) bool {
+	return errors.Is(err, gousb.ErrorTimeout) ||
+		errors.Is(err, gousb.ErrorInterrupted) ||
+		errors.Is(err, gousb.TransferTimedOut) ||
+		errors.Is(err, gousb.TransferCancelled)
+}
+
+// isRetryableError indicates if a write that failed with the given error may be repeated.
+// Errors caused by the given context are not retryable.
+func isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return errors.Is(err, gousb.ErrorBusy) ||
+		errors.Is(err, gousb.ErrorTimeout) ||
+		errors.Is(err, gousb.ErrorInterrupted) ||
+		errors.Is(err, gousb.TransferTimedOut)
+}
+
+// DecodeEvent decodes the control byte and the state byte of a frame that was received from the device
+// (bytes 9 and 10 of the frame, see ReadRawFrame). The Time of the returned event is not set. It returns an
+// error if the control byte is unknown.
+func DecodeEvent(controlByte uint8, stateByte uint8) (Event, error) {
+	return newEvent(hwControl(controlByte), stateByte)
 }
 
 func newEvent(control hwControl, state uint8) (Event, error) {
@@ -389,7 +886,7 @@ func newEvent(control hwControl, state uint8) (Event, error) {
 	case control == knobBottomRightCW, control == knobBottomRightCCW:
 		return newRotateEvent(KnobBottomRight, control)
 	default:
-		return Event{}, fmt.Errorf("unknown hw control: 0x%02x state: 0x%02x", control, state)
+		return Event{}, fmt.Errorf("%w: 0x%02x state: 0x%02x", ErrUnknownControl, control, state)
 	}
 }
 
@@ -402,106 +899,589 @@ func newPressEvent(control Control, state uint8) (Event, error) {
 
 func newRotateEvent(control Control, hwcontrol hwControl) (Event, error) {
 	action := TurnedCCW
+	steps := -1
 	if hwcontrol%2 == 1 {
 		action = TurnedCW
+		steps = 1
 	}
 
 	return Event{
 		Control: control,
 		Action:  action,
+		Steps:   steps,
 	}, nil
 }
 
-// SetBrightness in percent (0-100).
+// encodeEvent is the inverse of newEvent, it returns the hw control and state that represent the given event.
+func encodeEvent(e Event) (hwControl, uint8, error) {
+	rotated := func(cw hwControl, ccw hwControl) (hwControl, uint8, error) {
+		if e.Action == TurnedCW {
+			return cw, 0, nil
+		}
+		return ccw, 0, nil
+	}
+
+	switch {
+	case e.Action.IsPress() && e.Control.IsDisplay():
+		return hwControl(e.Control), uint8(e.Action), nil
+	case e.Action.IsPress() && e.Control == ButtonLeft:
+		return buttonLeft, uint8(e.Action), nil
+	case e.Action.IsPress() && e.Control == ButtonCenter:
+		return buttonCenter, uint8(e.Action), nil
+	case e.Action.IsPress() && e.Control == ButtonRight:
+		return buttonRight, uint8(e.Action), nil
+	case e.Action.IsPress() && e.Control == KnobTop:
+		return knobTop, uint8(e.Action), nil
+	case e.Action.IsPress() && e.Control == KnobBottomLeft:
+		return knobBottomLeft, uint8(e.Action), nil
+	case e.Action.IsPress() && e.Control == KnobBottomRight:
+		return knobBottomRight, uint8(e.Action), nil
+	case e.Action.IsRotation() && e.Control == KnobTop:
+		return rotated(knobTopCW, knobTopCCW)
+	case e.Action.IsRotation() && e.Control == KnobBottomLeft:
+		return rotated(knobBottomLeftCW, knobBottomLeftCCW)
+	case e.Action.IsRotation() && e.Control == KnobBottomRight:
+		return rotated(knobBottomRightCW, knobBottomRightCCW)
+	default:
+		return 0, 0, fmt.Errorf("the event %d/%d cannot be sent by the device", e.Control, e.Action)
+	}
+}
+
+// SetBrightness in percent (0-100). The perceived brightness follows the given percentage,
+// see WithBrightnessGamma.
 func (d *Device) SetBrightness(ctx context.Context, percent uint8) error {
 	if percent > 100 {
 		percent = 100
 	}
-	return d.sendCRTCommand(ctx, "LIG", percent)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.setBrightness(ctx, percent)
+}
+
+// setBrightness remembers and sends the given brightness in percent (0-100). The lock must be held when
+// calling setBrightness.
+func (d *Device) setBrightness(ctx context.Context, percent uint8) error {
+	d.brightness = percent
+	d.brightnessSet = true
+	if percent > 0 {
+		d.onBrightness = percent
+	}
+	d.lastActivity = time.Now()
+	d.dimmed = false
+
+	return d.recoverFrom(d.sendBrightness(ctx, percent))
+}
+
+// GetBrightness returns the brightness in percent (0-100) that was last set with SetBrightness.
+// If the brightness was not set yet, GetBrightness returns DefaultBrightness.
+func (d *Device) GetBrightness() uint8 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.currentBrightness()
+}
+
+// LastUpdated returns the point in time when the given display button was last written, either with a new
+// image or by clearing it. Updates that were skipped because the image did not change do not count. It returns
+// the zero time if the display button was not written yet or the given control is not a display.
+func (d *Device) LastUpdated(display Control) time.Time {
+	if !display.IsDisplay() {
+		return time.Time{}
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.updated[display-DisplayTopLeft]
 }
 
 // Clear the display buttons.
 func (d *Device) Clear(ctx context.Context) error {
-	err := d.sendCRTCommand(ctx, "CLE", 0x00, 0xff)
-	if err != nil {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.setImageState(allDisplays, nil)
+	return d.recoverFrom(d.clearDisplays(ctx, allDisplays))
+}
+
+// ClearButton clears only the given display button, the other display buttons are left untouched.
+func (d *Device) ClearButton(ctx context.Context, display Control) error {
+	if err := checkDisplay(display); err != nil {
 		return err
 	}
-	return d.sendCRTCommand(ctx, "STP")
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.setImageState(uint8(display), nil)
+	return d.recoverFrom(d.clearDisplays(ctx, uint8(display)))
 }
 
-// SetImage sets the image of a specific display button.
-func (d *Device) SetImage(ctx context.Context, display Control, img image.Image) error {
-	if !display.IsDisplay() {
-		return fmt.Errorf("the given control %d is not a display", display)
+// Reset puts the device into a known state: it repeats the initialization handshake, sets the brightness
+// to DefaultBrightness, and clears all display buttons. Reset may be called repeatedly, e.g. at startup or
+// to recover from errors.
+func (d *Device) Reset(ctx context.Context) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.brightness = DefaultBrightness
+	d.brightnessSet = true
+	d.onBrightness = DefaultBrightness
+	d.lastActivity = time.Now()
+	d.dimmed = false
+	d.setImageState(allDisplays, nil)
+	d.invalidateDisplayed()
+
+	return d.recoverFrom(d.reset(ctx))
+}
+
+func (d *Device) reset(ctx context.Context) error {
+	err := d.init(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot reset the device: %w", err)
+	}
+	err = d.sendBrightness(ctx, DefaultBrightness)
+	if err != nil {
+		return fmt.Errorf("cannot reset the brightness: %w", err)
+	}
+	err = d.clearDisplays(ctx, allDisplays)
+	if err != nil {
+		return fmt.Errorf("cannot reset the displays: %w", err)
 	}
+	return nil
+}
 
-	err := d.sendImage(ctx, uint8(display), img)
+// SetImage sets the image of a specific display button. If the updates of the display button are limited
+// with WithMaxFPS, the image may be sent later and SetImage returns without an error.
+func (d *Device) SetImage(ctx context.Context, display Control, img image.Image) error {
+	if err := checkDisplay(display); err != nil {
+		return err
+	}
+	err := checkImageSize(img)
 	if err != nil {
 		return err
 	}
-	return d.sendCRTCommand(ctx, "STP")
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.setImageState(uint8(display), img)
+	if d.throttle(uint8(display)) {
+		return nil
+	}
+	return d.recoverFrom(d.uploadImage(ctx, uint8(display), img))
 }
 
-// SetImages sets the images of all six display buttons at once.
-func (d *Device) SetImages(ctx context.Context, imgs [6]image.Image) error {
-	err := d.sendCRTCommand(ctx, "CLE", 0x00, 0xff)
-	if err != nil {
+// SetImageJPEG sets the image of a specific display button using the given JPEG data, which is sent to the
+// device without encoding it again. This is useful to show precomputed images. The caller is responsible that
+// the JPEG data contains an image with a size of ImageSize x ImageSize pixels. The size of the JPEG data is
+// limited to MaxImageBytes. The updates are limited like with SetImage, see WithMaxFPS.
+func (d *Device) SetImageJPEG(ctx context.Context, display Control, jpg []byte) error {
+	if err := checkDisplay(display); err != nil {
 		return err
 	}
+	if len(jpg) == 0 {
+		return errors.New("the JPEG data is empty")
+	}
+	if len(jpg) > MaxImageBytes {
+		return imageTooLargeError(len(jpg), MaxImageBytes)
+	}
 
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.setJPEGState(uint8(display), jpg)
+	if d.throttle(uint8(display)) {
+		return nil
+	}
+	return d.recoverFrom(d.uploadJPEG(ctx, uint8(display), d.jpegs[display-1]))
+}
+
+// SetImages sets the images of all six display buttons at once. Only the display buttons
+// whose image changed are sent to the device, use Refresh to send all images again.
+// Display buttons with a nil image are cleared, if all images are nil, SetImages clears
+// all display buttons like Clear, but only if at least one display button currently shows
+// an image. Otherwise, nothing is sent to the device. Use SetImage and ClearButton to change only some of
+// the display buttons. All images are checked and encoded before anything is sent to the
+// device, if one of the images is invalid, the display buttons are left unchanged. The updates are
+// limited like with SetImage, see WithMaxFPS.
+func (d *Device) SetImages(ctx context.Context, imgs [6]image.Image) error {
+	allNil := true
 	for i, img := range imgs {
 		if img == nil {
 			continue
 		}
-		err = d.sendImage(ctx, uint8(i+1), img)
+		allNil = false
+		err := checkImageSize(img)
 		if err != nil {
-			return err
+			return fmt.Errorf("invalid image for display %d: %w", i+1, err)
 		}
 	}
+	if allNil {
+		return d.clearImages(ctx)
+	}
+
+	jpgs, err := d.encodeImages(imgs)
+	if err != nil {
+		return err
+	}
 
-	return d.sendCRTCommand(ctx, "STP")
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.images = imgs
+	clear(d.jpegs[:])
+	d.keepThrottled(&jpgs)
+	return d.recoverFrom(d.uploadJPEGs(ctx, jpgs))
+}
+
+// clearImages clears all display buttons, unless all display buttons are already empty.
+func (d *Device) clearImages(ctx context.Context) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	empty := d.images == [6]image.Image{}
+	for _, jpg := range d.jpegs {
+		empty = empty && jpg == nil
+	}
+	if empty {
+		return nil
+	}
+	d.setImageState(allDisplays, nil)
+	return d.recoverFrom(d.clearDisplays(ctx, allDisplays))
+}
+
+// SetAllImages shows the given image on all six display buttons. The image is checked and encoded only
+// once. Only the display buttons whose image changed are sent to the device. If the image is nil, all display
+// buttons are cleared like Clear. The updates are limited like with SetImage, see WithMaxFPS.
+func (d *Device) SetAllImages(ctx context.Context, img image.Image) error {
+	if img == nil {
+		return d.Clear(ctx)
+	}
+	// encode the image only once for every rotation of the display buttons
+	var jpgs [6][]byte
+	encoded := make(map[int32][]byte)
+	for i := range jpgs {
+		rotation := d.rotations[i].Load()
+		jpg, ok := encoded[rotation]
+		if !ok {
+			var err error
+			jpg, err = d.encodeImage(uint8(i+1), img)
+			if err != nil {
+				return err
+			}
+			encoded[rotation] = jpg
+		}
+		jpgs[i] = jpg
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for i := range d.images {
+		d.images[i] = img
+	}
+	clear(d.jpegs[:])
+	d.keepThrottled(&jpgs)
+	return d.recoverFrom(d.uploadJPEGs(ctx, jpgs))
 }
 
-func (d *Device) sendCRTCommandWithTimeout(cmd string, args ...byte) error {
-	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+// Refresh sends the last set images of all display buttons to the device again, including those that
+// did not change. This can be used to repair the display if its state is out of sync.
+func (d *Device) Refresh(ctx context.Context) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.recoverFrom(d.refreshImages(ctx))
+}
+
+// SetButtonColor fills a specific display button with the given color.
+func (d *Device) SetButtonColor(ctx context.Context, display Control, c color.Color) error {
+	if err := checkDisplay(display); err != nil {
+		return err
+	}
+
+	return d.SetImage(ctx, display, uniformImage(c))
+}
+
+// SetAllButtonColors fills all six display buttons with the given color.
+func (d *Device) SetAllButtonColors(ctx context.Context, c color.Color) error {
+	return d.SetAllImages(ctx, uniformImage(c))
+}
+
+func (d *Device) sendCRTCommandWithTimeout(ctx context.Context, cmd string, args ...byte) error {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
 	defer cancel()
 
 	return d.sendCRTCommand(ctx, cmd, args...)
 }
 
-func (d *Device) sendCRTCommand(ctx context.Context, cmd string, args ...byte) error {
+// BuildCRTCommand returns the frame of the given CRT command with the given arguments, as it is sent to the
+// device: the prefix "CRT", two zero bytes, the command, two zero bytes, and the arguments. When the frame is
+// sent, it is padded with zeros to fill a packet of the OUT endpoint. For example, the command LIG with the
+// argument 0x64 results in the frame "CRT\x00\x00LIG\x00\x00\x64".
+func BuildCRTCommand(cmd string, args ...byte) []byte {
+	return appendCRTCommand(nil, cmd, args...)
+}
+
+func appendCRTCommand(frame []byte, cmd string, args ...byte) []byte {
 	const prefix = "CRT"
 
-	cmdBytes := make([]byte, 0, len(prefix)+2+len(cmd)+2+len(args))
-	cmdBytes = append(cmdBytes, []byte(prefix)...)
-	cmdBytes = append(cmdBytes, 0, 0)
-	cmdBytes = append(cmdBytes, []byte(cmd)...)
-	cmdBytes = append(cmdBytes, 0, 0)
-	cmdBytes = append(cmdBytes, args...)
+	frame = append(frame, prefix...)
+	frame = append(frame, 0, 0)
+	frame = append(frame, cmd...)
+	frame = append(frame, 0, 0)
+	frame = append(frame, args...)
+	return frame
+}
 
-	outbuf := make([]byte, d.epOut.Desc.MaxPacketSize)
-	copy(outbuf, cmdBytes)
+func (d *Device) sendCRTCommand(ctx context.Context, cmd string, args ...byte) error {
+	var frame [64]byte
+	cmdBytes := appendCRTCommand(frame[:0], cmd, args...)
 
-	n, err := d.writeData(ctx, outbuf)
+	start := time.Now()
+	n, err := d.writeCommand(ctx, cmdBytes)
+	d.reportMetric(cmd, n, start, err)
 	if err != nil {
 		return err
 	}
-	if n < len(outbuf) {
-		return fmt.Errorf("sendCRTCommand: %d bytes written, expected %d bytes", n, len(outbuf))
+	if n < len(cmdBytes) {
+		return fmt.Errorf("sendCRTCommand: %d bytes written, expected %d bytes", n, len(cmdBytes))
 	}
 
 	return nil
 }
 
-func (d *Device) sendImage(ctx context.Context, index uint8, img image.Image) error {
-	if img.Bounds().Max.X != ImageSize || img.Bounds().Max.Y != ImageSize {
-		return fmt.Errorf("sendImage: the image must have a size of %dx%d pixels", ImageSize, ImageSize)
+// writeCommand writes the given command and repeats it if it failed with a transient error.
+func (d *Device) writeCommand(ctx context.Context, cmdBytes []byte) (int, error) {
+	// writeData pads the command with zeros to fill the packet
+	n, err := d.writeData(ctx, cmdBytes)
+	for attempt := 1; err != nil && attempt <= d.settings.writeRetries && isRetryableError(ctx, err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(time.Duration(attempt) * writeRetryBackoff):
+		}
+		n, err = d.writeData(ctx, cmdBytes)
+	}
+	return n, err
+}
+
+// reportMetric passes the metric of an operation that was started at the given time to the metrics handler.
+func (d *Device) reportMetric(operation string, bytes int, start time.Time, err error) {
+	if d.settings.metricsHandler == nil {
+		return
+	}
+	d.settings.metricsHandler(Metric{
+		Operation: operation,
+		Bytes:     bytes,
+		Duration:  time.Since(start),
+		Err:       err,
+	})
+}
+
+// clearDisplays clears the display button with the given index, or all display buttons if index is allDisplays,
+// and commits the change.
+func (d *Device) clearDisplays(ctx context.Context, index uint8) error {
+	err := d.sendClear(ctx, index)
+	if err != nil {
+		return err
+	}
+	return d.commit(ctx)
+}
+
+// commit makes the changes of the display buttons visible.
+func (d *Device) commit(ctx context.Context) error {
+	err := d.sendCRTCommand(ctx, "STP")
+	if err != nil {
+		return fmt.Errorf("cannot commit the changes of the displays: %w", err)
+	}
+	return nil
+}
+
+// uploadImage sends the image of the display button with the given index and commits the change.
+// If the display button already shows the same image, nothing is sent.
+func (d *Device) uploadImage(ctx context.Context, index uint8, img image.Image) error {
+	jpg, err := d.encodeImage(index, img)
+	if err != nil {
+		return err
+	}
+	return d.uploadJPEG(ctx, index, jpg)
+}
+
+// uploadJPEG sends the JPEG data of the display button with the given index and commits the change.
+// If the display button already shows the same image, nothing is sent.
+func (d *Device) uploadJPEG(ctx context.Context, index uint8, jpg []byte) error {
+	if d.isDisplayed(index, jpg) {
+		return nil
+	}
+
+	err := d.sendJPEG(ctx, index, jpg)
+	if err != nil {
+		return err
+	}
+	return d.commit(ctx)
+}
+
+// uploadJPEGs replaces the images of all display buttons with the given JPEG data and commits the change.
+// Display buttons with nil data stay blank. Only the display buttons that do not already show the same
+// image are updated.
+func (d *Device) uploadJPEGs(ctx context.Context, jpgs [6][]byte) error {
+	changed := false
+	if !d.displayedKnown {
+		err := d.sendClear(ctx, allDisplays)
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+
+	for i, jpg := range jpgs {
+		index := uint8(i + 1)
+		if jpg == nil {
+			if d.displayed[i] == nil {
+				continue
+			}
+			err := d.sendClear(ctx, index)
+			if err != nil {
+				return err
+			}
+			changed = true
+			continue
+		}
+
+		if d.isDisplayed(index, jpg) {
+			continue
+		}
+		err := d.sendJPEG(ctx, index, jpg)
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
 	}
+	return d.commit(ctx)
+}
 
-	jpg, err := toJPEG(img)
+// refreshImages sends the last set images of all display buttons to the device, regardless of the images
+// that are already displayed.
+func (d *Device) refreshImages(ctx context.Context) error {
+	jpgs, err := d.encodeImages(d.images)
 	if err != nil {
 		return err
 	}
+	for i, jpg := range d.jpegs {
+		if jpg != nil {
+			jpgs[i] = jpg
+		}
+	}
+
+	d.invalidateDisplayed()
+	return d.uploadJPEGs(ctx, jpgs)
+}
+
+// isDisplayed indicates if the display button with the given index already shows the given JPEG data.
+func (d *Device) isDisplayed(index uint8, jpg []byte) bool {
+	return d.displayedKnown && bytes.Equal(d.displayed[index-1], jpg)
+}
+
+// invalidateDisplayed forgets which images are displayed, e.g. because the device lost its state.
+func (d *Device) invalidateDisplayed() {
+	clear(d.displayed[:])
+	d.displayedKnown = false
+}
+
+// sendClear clears the display button with the given index, or all display buttons if index is allDisplays.
+func (d *Device) sendClear(ctx context.Context, index uint8) error {
+	err := d.sendCRTCommand(ctx, "CLE", 0x00, index)
+	if err != nil && index == allDisplays {
+		return fmt.Errorf("cannot clear all displays: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot clear display %d: %w", index, err)
+	}
+
+	now := time.Now()
+	if index == allDisplays {
+		clear(d.displayed[:])
+		d.displayedKnown = true
+		for i := range d.updated {
+			d.updated[i] = now
+		}
+	} else {
+		d.displayed[index-1] = nil
+		d.updated[index-1] = now
+	}
+	return nil
+}
+
+// encodeImage encodes the given image for the display button with the given index (1-6), rotated as set
+// with WithRotation or SetRotation.
+func (d *Device) encodeImage(index uint8, img image.Image) ([]byte, error) {
+	err := checkImageSize(img)
+	if err != nil {
+		return nil, err
+	}
+	img = rotateImage(img, int(d.rotations[index-1].Load()))
+	img = flattenImage(img, d.settings.background)
+
+	jpg, err := d.settings.encoder.Encode(img)
+	if err != nil {
+		return nil, err
+	}
+	if len(jpg) <= d.settings.maxImageBytes {
+		return jpg, nil
+	}
+
+	// lower the quality until the image fits, if possible
+	encoder, ok := d.settings.encoder.(JPEGEncoder)
+	for ok && encoder.Quality > 1 {
+		encoder.Quality = max(1, encoder.Quality-jpegQualityStep)
+		jpg, err = encoder.Encode(img)
+		if err != nil {
+			return nil, err
+		}
+		if len(jpg) <= d.settings.maxImageBytes {
+			return jpg, nil
+		}
+	}
+	return nil, imageTooLargeError(len(jpg), d.settings.maxImageBytes)
+}
+
+// encodeImages encodes the given images concurrently. The result contains nil for nil images.
+func (d *Device) encodeImages(imgs [6]image.Image) ([6][]byte, error) {
+	var result [6][]byte
+	var errs [6]error
+
+	var wg sync.WaitGroup
+	for i, img := range imgs {
+		if img == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result[i], errs[i] = d.encodeImage(uint8(i+1), img)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("cannot encode the image of display %d: %w", i+1, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return result, errors.Join(errs[:]...)
+}
+
+func (d *Device) sendJPEG(ctx context.Context, index uint8, jpg []byte) error {
+	if len(jpg) > MaxImageBytes {
+		return imageTooLargeError(len(jpg), MaxImageBytes)
+	}
+	d.displayed[index-1] = nil
 
 	imageSize := uint16(len(jpg))
 	args := []byte{
@@ -509,39 +1489,62 @@ func (d *Device) sendImage(ctx context.Context, index uint8, img image.Image) er
 		byte(uint8(imageSize & 0x00ff)),
 		index,
 	}
-	err = d.sendCRTCommand(ctx, "BAT", args...)
+	err := d.sendCRTCommand(ctx, "BAT", args...)
 	if err != nil {
-		return err
+		return fmt.Errorf("cannot announce the image of display %d: %w", index, err)
 	}
 
+	start := time.Now()
 	n, err := d.writeData(ctx, jpg)
+	d.reportMetric(MetricImageData, n, start, err)
 	if err != nil {
-		return err
+		return fmt.Errorf("cannot transfer the image of display %d: %w", index, err)
 	}
 	if n < int(imageSize) {
-		return fmt.Errorf("sendImage: %d bytes written, expected %d bytes", n, imageSize)
+		return fmt.Errorf("cannot transfer the image of display %d: %d bytes written, expected %d bytes", index, n, imageSize)
 	}
 
+	d.displayed[index-1] = jpg
+	d.updated[index-1] = time.Now()
+
 	return nil
 }
 
 func (d *Device) writeData(ctx context.Context, data []byte) (int, error) {
+	if d.aborted() {
+		return 0, ErrClosed
+	}
+	if d.transport == nil {
+		if d.isClosed() {
+			return 0, ErrClosed
+		}
+		return 0, ErrNotConnected
+	}
 	bytesWritten := 0
-	chunkSize := d.epOut.Desc.MaxPacketSize
-	chunk := make([]byte, chunkSize)
+	outEndpoint := d.transport.OutEndpoint()
+	chunkSize := outEndpoint.MaxPacketSize
+	if chunkSize <= 0 {
+		return 0, fmt.Errorf("writeData: invalid packet size %d", chunkSize)
+	}
+	chunk := d.getPacket(chunkSize)
+	defer d.putPacket(chunk)
 	for i := 0; i < len(data); i += chunkSize {
-		d.maintainPollInterval()
+		d.maintainPollInterval(outEndpoint.PollInterval)
 
 		clear(chunk)
 		end := min(i+chunkSize, len(data))
 		copy(chunk, data[i:end])
 
-		n, err := d.epOut.WriteContext(ctx, chunk)
-		if err != nil {
-			return 0, err
-		}
-		if n != chunkSize {
-			return 0, fmt.Errorf("writeData: %d bytes written, expected %d bytes", n, chunkSize)
+		// continue a short write with the rest of the packet
+		for written := 0; written < chunkSize; {
+			n, err := d.writePacket(ctx, chunk[written:])
+			if err != nil {
+				return bytesWritten, err
+			}
+			if n <= 0 {
+				return bytesWritten, fmt.Errorf("writeData: %d of %d bytes written", written, chunkSize)
+			}
+			written += n
 		}
 		bytesWritten = end
 	}
@@ -549,9 +1552,46 @@ func (d *Device) writeData(ctx context.Context, data []byte) (int, error) {
 	return bytesWritten, nil
 }
 
-func (d *Device) maintainPollInterval() {
+// writePacket writes one packet to the OUT endpoint. If the given context has no deadline, the write is
+// bounded by commandTimeout, so a device that does not take the data cannot block the caller forever.
+// The write is aborted when the device is closed.
+func (d *Device) writePacket(ctx context.Context, packet []byte) (int, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+	}
+	if !d.isClosed() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(d.ops, cancel)
+		defer stop()
+	}
+
+	n, err := d.transport.Write(ctx, packet)
+	if err != nil && d.aborted() {
+		return n, ErrClosed
+	}
+	return n, err
+}
+
+// getPacket returns a buffer with the given size from the device's pool of packet buffers.
+func (d *Device) getPacket(size int) []byte {
+	if p, ok := d.packets.Get().(*[]byte); ok && cap(*p) >= size {
+		return (*p)[:size]
+	}
+	return make([]byte, size)
+}
+
+// putPacket returns the given buffer to the device's pool of packet buffers.
+func (d *Device) putPacket(packet []byte) {
+	d.packets.Put(&packet)
+}
+
+func (d *Device) maintainPollInterval(pollInterval time.Duration) {
 	now := time.Now()
-	nextWrite := d.lastOut.Add(d.epOut.Desc.PollInterval)
+	nextWrite := d.lastOut.Add(pollInterval)
 	if nextWrite.After(now) {
 		waitDuration := nextWrite.Sub(now)
 		time.Sleep(waitDuration)
@@ -559,14 +1599,18 @@ func (d *Device) maintainPollInterval() {
 	d.lastOut = time.Now()
 }
 
-func toJPEG(img image.Image) ([]byte, error) {
-	buffer := bytes.NewBuffer([]byte{})
-	opts := jpeg.Options{
-		Quality: 100,
+func checkImageSize(img image.Image) error {
+	if img == nil {
+		return fmt.Errorf("%w: the image is nil", ErrInvalidImageSize)
 	}
-	err := jpeg.Encode(buffer, img, &opts)
-	if err != nil {
-		return nil, err
+	if img.Bounds().Max.X != ImageSize || img.Bounds().Max.Y != ImageSize {
+		return fmt.Errorf("%w: the image must have a size of %dx%d pixels", ErrInvalidImageSize, ImageSize, ImageSize)
 	}
-	return buffer.Bytes(), err
+	return nil
+}
+
+func uniformImage(c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, ImageSize, ImageSize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
 }
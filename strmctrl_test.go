@@ -0,0 +1,440 @@
+package strmctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestSimulator(t testing.TB, opts ...Option) *Simulator {
+	t.Helper()
+	opts = append([]Option{WithSettleTime(0), WithKeepAliveInterval(0), WithLogger(nil)}, opts...)
+	sim, err := NewSimulator(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sim.Close)
+	return sim
+}
+
+func TestReadEventsAfterClose(t *testing.T) {
+	sim := newTestSimulator(t)
+	sim.Close()
+
+	_, err := sim.ReadEvents(context.Background())
+
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestReadEventsWithoutTransport(t *testing.T) {
+	sim := newTestSimulator(t)
+	sim.lock.Lock()
+	sim.disconnect()
+	sim.lock.Unlock()
+
+	events, err := sim.ReadEvents(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected the channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the channel was not closed")
+	}
+	if !errors.Is(sim.Err(), ErrNotConnected) {
+		t.Errorf("expected ErrNotConnected, got %v", sim.Err())
+	}
+}
+
+func TestSetImagesAllNil(t *testing.T) {
+	var commands []string
+	sim := newTestSimulator(t, WithMetrics(func(m Metric) {
+		if m.Operation != MetricImageData {
+			commands = append(commands, m.Operation)
+		}
+	}))
+	ctx := context.Background()
+	commands = nil
+
+	if err := sim.SetImages(ctx, [6]image.Image{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 0 {
+		t.Errorf("expected no commands for empty displays, got %v", commands)
+	}
+
+	if err := sim.SetImage(ctx, DisplayTopLeft, uniformImage(color.White)); err != nil {
+		t.Fatal(err)
+	}
+	commands = nil
+	if err := sim.SetImages(ctx, [6]image.Image{}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(commands, "CLE") {
+		t.Errorf("expected CLE to clear the displays, got %v", commands)
+	}
+	if img, _ := sim.Image(DisplayTopLeft); img != nil {
+		t.Error("expected the display to be cleared")
+	}
+
+	commands = nil
+	if err := sim.SetImages(ctx, [6]image.Image{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 0 {
+		t.Errorf("expected no commands for already cleared displays, got %v", commands)
+	}
+}
+
+func TestBuildCRTCommand(t *testing.T) {
+	tt := []struct {
+		cmd      string
+		args     []byte
+		expected string
+	}{
+		{"DIS", nil, "CRT\x00\x00DIS\x00\x00"},
+		{"CONNECT", nil, "CRT\x00\x00CONNECT\x00\x00"},
+		{"CLE", []byte{0x00, allDisplays}, "CRT\x00\x00CLE\x00\x00\x00\xff"},
+		{"CLE", []byte{0x00, uint8(DisplayTopLeft)}, "CRT\x00\x00CLE\x00\x00\x00\x01"},
+		{"LIG", []byte{0x64}, "CRT\x00\x00LIG\x00\x00\x64"},
+		{"LIG", []byte{0x00}, "CRT\x00\x00LIG\x00\x00\x00"},
+		{"BAT", []byte{0x12, 0x34, 0x06}, "CRT\x00\x00BAT\x00\x00\x12\x34\x06"},
+	}
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("%s_% x", tc.cmd, tc.args), func(t *testing.T) {
+			actual := BuildCRTCommand(tc.cmd, tc.args...)
+			if string(actual) != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestActionKind(t *testing.T) {
+	tt := []struct {
+		action   Action
+		press    bool
+		down     bool
+		rotation bool
+	}{
+		{Released, true, false, false},
+		{Pressed, true, true, false},
+		{TurnedCW, false, false, true},
+		{TurnedCCW, false, false, true},
+		{LongPressed, false, false, false},
+		{DoublePressed, false, false, false},
+	}
+	if len(tt) != len(actionNames) {
+		t.Fatalf("expected all %d actions to be covered, got %d", len(actionNames), len(tt))
+	}
+	for _, tc := range tt {
+		t.Run(tc.action.String(), func(t *testing.T) {
+			if tc.action.IsPress() != tc.press {
+				t.Errorf("IsPress: expected %t", tc.press)
+			}
+			if tc.action.IsDown() != tc.down {
+				t.Errorf("IsDown: expected %t", tc.down)
+			}
+			if tc.action.IsRotation() != tc.rotation {
+				t.Errorf("IsRotation: expected %t", tc.rotation)
+			}
+		})
+	}
+}
+
+func TestControlString(t *testing.T) {
+	names := make(map[string]Control)
+	for _, c := range seControls {
+		name := c.String()
+		if name == "" || strings.HasPrefix(name, "Control(") {
+			t.Errorf("control %d has no name", uint8(c))
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("controls %d and %d have the same name %q", uint8(other), uint8(c), name)
+		}
+		names[name] = c
+	}
+	if len(names) != len(controlNames) {
+		t.Errorf("expected %d names, got %d", len(controlNames), len(names))
+	}
+
+	for _, c := range []Control{0, 42, 255} {
+		expected := fmt.Sprintf("Control(%d)", uint8(c))
+		if c.String() != expected {
+			t.Errorf("expected %q, got %q", expected, c.String())
+		}
+	}
+}
+
+func TestActionString(t *testing.T) {
+	actions := []Action{Released, Pressed, TurnedCW, TurnedCCW, LongPressed, DoublePressed}
+	names := make(map[string]Action)
+	for _, a := range actions {
+		name := a.String()
+		if name == "" || strings.HasPrefix(name, "Action(") {
+			t.Errorf("action %d has no name", uint8(a))
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("actions %d and %d have the same name %q", uint8(other), uint8(a), name)
+		}
+		names[name] = a
+	}
+	if len(names) != len(actionNames) {
+		t.Errorf("expected %d names, got %d", len(actionNames), len(names))
+	}
+
+	for _, a := range []Action{DoublePressed + 1, 42, 255} {
+		expected := fmt.Sprintf("Action(%d)", uint8(a))
+		if a.String() != expected {
+			t.Errorf("expected %q, got %q", expected, a.String())
+		}
+	}
+}
+
+// testTiles returns six different full-colour images for the display buttons. Tiles with different seeds
+// differ in every display button.
+func testTiles(seed int) [6]image.Image {
+	var result [6]image.Image
+	for i := range result {
+		img := image.NewRGBA(image.Rect(0, 0, ImageSize, ImageSize))
+		for y := range ImageSize {
+			for x := range ImageSize {
+				img.Set(x, y, color.RGBA{
+					R: uint8(x*4 + seed),
+					G: uint8(y*4 + i*40),
+					B: uint8((x+y)*2 + seed*7),
+					A: 0xff,
+				})
+			}
+		}
+		result[i] = img
+	}
+	return result
+}
+
+func BenchmarkEncodeImages(b *testing.B) {
+	sim := newTestSimulator(b)
+	tiles := testTiles(0)
+
+	b.Run("sequential", func(b *testing.B) {
+		for b.Loop() {
+			for i, img := range tiles {
+				_, err := sim.encodeImage(uint8(i+1), img)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for b.Loop() {
+			_, err := sim.encodeImages(tiles)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// discardTransport drops all written packets, so that benchmarks only measure the device's own work.
+type discardTransport struct {
+	*simTransport
+}
+
+func (discardTransport) Write(_ context.Context, packet []byte) (int, error) {
+	return len(packet), nil
+}
+
+func BenchmarkSendCRTCommand(b *testing.B) {
+	device, err := OpenTransport(discardTransport{newSimTransport()}, WithSettleTime(0), WithKeepAliveInterval(0), WithLogger(nil))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer device.Close()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		device.lock.Lock()
+		err := device.sendCRTCommand(ctx, "LIG", 0x64)
+		device.lock.Unlock()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestConcurrentBrightnessAndImages(t *testing.T) {
+	sim := newTestSimulator(t)
+	ctx := context.Background()
+	frames := [2][6]image.Image{testTiles(0), testTiles(1)}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			err := sim.SetBrightness(ctx, uint8(i%2*50+50))
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := range 20 {
+			err := sim.SetImages(ctx, frames[i%2])
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	for _, display := range seControls[:6] {
+		img, err := sim.Image(display)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img == nil {
+			t.Errorf("expected an image on %v", display)
+		}
+	}
+	if sim.RawBrightness() != applyGamma(sim.GetBrightness(), DefaultBrightnessGamma) {
+		t.Errorf("expected the raw brightness of %d%%, got %d", sim.GetBrightness(), sim.RawBrightness())
+	}
+}
+
+func TestCloseDuringSetImages(t *testing.T) {
+	for range 10 {
+		sim := newTestSimulator(t)
+		ctx := context.Background()
+		frames := [2][6]image.Image{testTiles(0), testTiles(1)}
+
+		done := make(chan error)
+		go func() {
+			defer close(done)
+			for i := 0; ; i++ {
+				err := sim.SetImages(ctx, frames[i%2])
+				if err != nil {
+					done <- err
+					return
+				}
+			}
+		}()
+		time.Sleep(5 * time.Millisecond)
+		sim.Close()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrClosed) {
+				t.Errorf("expected ErrClosed, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("SetImages was not aborted by Close")
+		}
+	}
+}
+
+func TestReadEventsTruncatedFrame(t *testing.T) {
+	sim := newTestSimulator(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	events, err := sim.ReadEvents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := Event{Control: ButtonLeft, Action: Pressed}
+	last := Event{Control: ButtonRight, Action: Pressed}
+	control, _, err := encodeEvent(Event{Control: KnobTop, Action: Pressed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the truncated frame lacks the state, the read buffer still holds the state of the previous frame
+	truncated := make([]byte, eventFrameSize-1)
+	truncated[9] = byte(control)
+	sim.Inject(first)
+	sim.transport.inject(truncated)
+	sim.transport.inject([]byte{})
+	sim.Inject(last)
+
+	for _, expected := range []Event{first, last} {
+		select {
+		case e := <-events:
+			if e.Control != expected.Control || e.Action != expected.Action {
+				t.Errorf("expected %v %v, got %v %v", expected.Control, expected.Action, e.Control, e.Action)
+			}
+		case <-ctx.Done():
+			t.Fatalf("expected %v %v, got nothing", expected.Control, expected.Action)
+		}
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %v %v", e.Control, e.Action)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestDeliveryDropOldest(t *testing.T) {
+	const buffer = 3
+	sim := newTestSimulator(t, WithDeliveryPolicy(DeliveryDropOldest), WithEventBuffer(buffer))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	events, err := sim.ReadEvents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the consumer is stalled while the device sends the events
+	var injected []Event
+	for _, control := range seControls[:10] {
+		e := Event{Control: control, Action: Pressed}
+		injected = append(injected, e)
+		sim.Inject(e)
+	}
+	for len(sim.transport.frames) > 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("the read loop stopped")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	for _, expected := range injected[len(injected)-buffer:] {
+		select {
+		case e := <-events:
+			if e.Control != expected.Control || e.Action != expected.Action {
+				t.Errorf("expected %v %v, got %v %v", expected.Control, expected.Action, e.Control, e.Action)
+			}
+		case <-ctx.Done():
+			t.Fatalf("expected %v %v, got nothing", expected.Control, expected.Action)
+		}
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %v %v", e.Control, e.Action)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
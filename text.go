@@ -0,0 +1,320 @@
+package strmctrl
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+const (
+	// textMargin is the minimal distance between the text and the border of a button image in pixels.
+	textMargin = 2
+)
+
+// Font is a monospaced bitmap font that is used to render text onto button images.
+type Font struct {
+	// Width and Height of a glyph in font pixels.
+	Width, Height int
+	// Glyphs contains the columns of each glyph from left to right. Bit n of a column is set
+	// if the pixel in row n (from the top) is set.
+	Glyphs map[rune][]uint32
+	// Fallback is rendered for runes that have no glyph.
+	Fallback rune
+}
+
+func (f *Font) glyph(r rune) []uint32 {
+	if glyph, ok := f.Glyphs[r]; ok {
+		return glyph
+	}
+	return f.Glyphs[f.Fallback]
+}
+
+// Alignment defines the horizontal alignment of text.
+type Alignment int
+
+const (
+	AlignCenter Alignment = iota
+	AlignLeft
+	AlignRight
+)
+
+// TextOptions control how TextImage renders the text.
+type TextOptions struct {
+	// Foreground is the color of the text, white by default.
+	Foreground color.Color
+	// Background is the color of the background, black by default.
+	Background color.Color
+	// Font is used to render the text, DefaultFont by default.
+	Font *Font
+	// Size is the size of a font pixel in image pixels. If zero, the largest size is used that fits
+	// the text onto the button without breaking words. The size is limited so that a single glyph
+	// fits onto the button.
+	Size int
+	// Alignment is the horizontal alignment of the lines, centered by default.
+	Alignment Alignment
+}
+
+func (o TextOptions) withDefaults() TextOptions {
+	if o.Foreground == nil {
+		o.Foreground = color.White
+	}
+	if o.Background == nil {
+		o.Background = color.Black
+	}
+	if o.Font == nil {
+		o.Font = DefaultFont
+	}
+	return o
+}
+
+// TextImage renders the given label centered onto an image for a display button. The label is wrapped
+// at spaces and line breaks, words that are too long for a line are broken. If the label does not fit
+// onto the button, it is truncated and ends with an ellipsis.
+func TextImage(label string, opts TextOptions) image.Image {
+	opts = opts.withDefaults()
+
+	img := image.NewRGBA(image.Rect(0, 0, ImageSize, ImageSize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+
+	area := image.Rect(textMargin, textMargin, ImageSize-textMargin, ImageSize-textMargin)
+	lines, scale := layoutText(label, opts.Font, opts.Size, area.Dx(), area.Dy())
+	drawText(img, area, lines, scale, opts)
+
+	return img
+}
+
+// drawText draws the given lines vertically centered into the given area of the image.
+func drawText(img draw.Image, area image.Rectangle, lines [][]rune, scale int, opts TextOptions) {
+	if scale < 1 {
+		return
+	}
+
+	font := opts.Font
+	fill := image.NewUniform(opts.Foreground)
+	advance := (font.Width + 1) * scale
+	lineHeight := (font.Height + 1) * scale
+	y := area.Min.Y + (area.Dy()-(len(lines)*lineHeight-scale))/2
+	for _, line := range lines {
+		width := len(line)*advance - scale
+		var x int
+		switch opts.Alignment {
+		case AlignLeft:
+			x = area.Min.X
+		case AlignRight:
+			x = area.Max.X - width
+		default:
+			x = area.Min.X + (area.Dx()-width)/2
+		}
+		for _, r := range line {
+			drawGlyph(img, font.glyph(r), font.Height, x, y, scale, fill)
+			x += advance
+		}
+		y += lineHeight
+	}
+}
+
+func drawGlyph(img draw.Image, glyph []uint32, height, x, y, scale int, fill image.Image) {
+	for col, bits := range glyph {
+		for row := range height {
+			if bits&(1<<row) == 0 {
+				continue
+			}
+			pixel := image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale)
+			draw.Draw(img, pixel, fill, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// layoutText wraps the given text for the given font and an area with the given width and height.
+// It returns the lines and the scale of the font pixels. If size is zero, the largest scale is
+// selected that fits the text without breaking words.
+func layoutText(text string, font *Font, size int, width, height int) ([][]rune, int) {
+	if font.Width < 1 || font.Height < 1 {
+		return nil, 0
+	}
+	maxScale := min(width/font.Width, height/font.Height)
+	if maxScale < 1 {
+		return nil, 0
+	}
+	columns := func(scale int) int { return (width + scale) / ((font.Width + 1) * scale) }
+	rows := func(scale int) int { return (height + scale) / ((font.Height + 1) * scale) }
+
+	scale := min(size, maxScale)
+	if size <= 0 {
+		longestWord := 0
+		for _, word := range strings.Fields(text) {
+			longestWord = max(longestWord, len([]rune(word)))
+		}
+		for scale = maxScale; scale > 1; scale-- {
+			if longestWord <= columns(scale) && len(wrapText(text, columns(scale))) <= rows(scale) {
+				break
+			}
+		}
+	}
+
+	lines := wrapText(text, columns(scale))
+	return truncateLines(lines, rows(scale), columns(scale)), scale
+}
+
+// wrapText breaks the given text into lines of at most the given number of columns.
+func wrapText(text string, columns int) [][]rune {
+	var result [][]rune
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		for _, field := range strings.Fields(paragraph) {
+			word := []rune(field)
+			for len(word) > columns {
+				if len(line) > 0 {
+					result = append(result, line)
+					line = nil
+				}
+				result = append(result, word[:columns])
+				word = word[columns:]
+			}
+			switch {
+			case len(word) == 0:
+			case len(line) == 0:
+				line = word
+			case len(line)+1+len(word) <= columns:
+				line = append(append(line, ' '), word...)
+			default:
+				result = append(result, line)
+				line = word
+			}
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// truncateLines limits the given lines to the given number of rows. If lines are dropped, the last
+// remaining line ends with an ellipsis.
+func truncateLines(lines [][]rune, rows int, columns int) [][]rune {
+	if len(lines) <= rows {
+		return lines
+	}
+	lines = lines[:rows]
+	last := lines[rows-1]
+	if len(last) >= columns {
+		last = last[:columns-1]
+	}
+	lines[rows-1] = append(last[:len(last):len(last)], '…')
+	return lines
+}
+
+// DefaultFont is a 5x7 bitmap font that contains the printable ASCII characters.
+var DefaultFont = &Font{
+	Width:    5,
+	Height:   7,
+	Glyphs:   defaultGlyphs(),
+	Fallback: '?',
+}
+
+func defaultGlyphs() map[rune][]uint32 {
+	result := make(map[rune][]uint32, len(defaultFontData)+1)
+	for i, columns := range defaultFontData {
+		result[rune(' '+i)] = columns[:]
+	}
+	result['…'] = []uint32{0x40, 0x00, 0x40, 0x00, 0x40}
+	return result
+}
+
+// defaultFontData contains the glyphs of the DefaultFont, starting with ' '.
+var defaultFontData = [...][5]uint32{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x00, 0x00, 0x5f, 0x00, 0x00}, // !
+	{0x00, 0x07, 0x00, 0x07, 0x00}, // "
+	{0x14, 0x7f, 0x14, 0x7f, 0x14}, // #
+	{0x24, 0x2a, 0x7f, 0x2a, 0x12}, // $
+	{0x23, 0x13, 0x08, 0x64, 0x62}, // %
+	{0x36, 0x49, 0x55, 0x22, 0x50}, // &
+	{0x00, 0x05, 0x03, 0x00, 0x00}, // '
+	{0x00, 0x1c, 0x22, 0x41, 0x00}, // (
+	{0x00, 0x41, 0x22, 0x1c, 0x00}, // )
+	{0x08, 0x2a, 0x1c, 0x2a, 0x08}, // *
+	{0x08, 0x08, 0x3e, 0x08, 0x08}, // +
+	{0x00, 0x50, 0x30, 0x00, 0x00}, // ,
+	{0x08, 0x08, 0x08, 0x08, 0x08}, // -
+	{0x00, 0x60, 0x60, 0x00, 0x00}, // .
+	{0x20, 0x10, 0x08, 0x04, 0x02}, // /
+	{0x3e, 0x51, 0x49, 0x45, 0x3e}, // 0
+	{0x00, 0x42, 0x7f, 0x40, 0x00}, // 1
+	{0x42, 0x61, 0x51, 0x49, 0x46}, // 2
+	{0x21, 0x41, 0x45, 0x4b, 0x31}, // 3
+	{0x18, 0x14, 0x12, 0x7f, 0x10}, // 4
+	{0x27, 0x45, 0x45, 0x45, 0x39}, // 5
+	{0x3c, 0x4a, 0x49, 0x49, 0x30}, // 6
+	{0x01, 0x71, 0x09, 0x05, 0x03}, // 7
+	{0x36, 0x49, 0x49, 0x49, 0x36}, // 8
+	{0x06, 0x49, 0x49, 0x29, 0x1e}, // 9
+	{0x00, 0x36, 0x36, 0x00, 0x00}, // :
+	{0x00, 0x56, 0x36, 0x00, 0x00}, // ;
+	{0x08, 0x14, 0x22, 0x41, 0x00}, // <
+	{0x14, 0x14, 0x14, 0x14, 0x14}, // =
+	{0x00, 0x41, 0x22, 0x14, 0x08}, // >
+	{0x02, 0x01, 0x51, 0x09, 0x06}, // ?
+	{0x32, 0x49, 0x79, 0x41, 0x3e}, // @
+	{0x7e, 0x11, 0x11, 0x11, 0x7e}, // A
+	{0x7f, 0x49, 0x49, 0x49, 0x36}, // B
+	{0x3e, 0x41, 0x41, 0x41, 0x22}, // C
+	{0x7f, 0x41, 0x41, 0x22, 0x1c}, // D
+	{0x7f, 0x49, 0x49, 0x49, 0x41}, // E
+	{0x7f, 0x09, 0x09, 0x01, 0x01}, // F
+	{0x3e, 0x41, 0x41, 0x51, 0x32}, // G
+	{0x7f, 0x08, 0x08, 0x08, 0x7f}, // H
+	{0x00, 0x41, 0x7f, 0x41, 0x00}, // I
+	{0x20, 0x40, 0x41, 0x3f, 0x01}, // J
+	{0x7f, 0x08, 0x14, 0x22, 0x41}, // K
+	{0x7f, 0x40, 0x40, 0x40, 0x40}, // L
+	{0x7f, 0x02, 0x04, 0x02, 0x7f}, // M
+	{0x7f, 0x04, 0x08, 0x10, 0x7f}, // N
+	{0x3e, 0x41, 0x41, 0x41, 0x3e}, // O
+	{0x7f, 0x09, 0x09, 0x09, 0x06}, // P
+	{0x3e, 0x41, 0x51, 0x21, 0x5e}, // Q
+	{0x7f, 0x09, 0x19, 0x29, 0x46}, // R
+	{0x46, 0x49, 0x49, 0x49, 0x31}, // S
+	{0x01, 0x01, 0x7f, 0x01, 0x01}, // T
+	{0x3f, 0x40, 0x40, 0x40, 0x3f}, // U
+	{0x1f, 0x20, 0x40, 0x20, 0x1f}, // V
+	{0x7f, 0x20, 0x18, 0x20, 0x7f}, // W
+	{0x63, 0x14, 0x08, 0x14, 0x63}, // X
+	{0x03, 0x04, 0x78, 0x04, 0x03}, // Y
+	{0x61, 0x51, 0x49, 0x45, 0x43}, // Z
+	{0x00, 0x7f, 0x41, 0x41, 0x00}, // [
+	{0x02, 0x04, 0x08, 0x10, 0x20}, // \
+	{0x00, 0x41, 0x41, 0x7f, 0x00}, // ]
+	{0x04, 0x02, 0x01, 0x02, 0x04}, // ^
+	{0x40, 0x40, 0x40, 0x40, 0x40}, // _
+	{0x00, 0x01, 0x02, 0x04, 0x00}, // `
+	{0x20, 0x54, 0x54, 0x54, 0x78}, // a
+	{0x7f, 0x48, 0x44, 0x44, 0x38}, // b
+	{0x38, 0x44, 0x44, 0x44, 0x20}, // c
+	{0x38, 0x44, 0x44, 0x48, 0x7f}, // d
+	{0x38, 0x54, 0x54, 0x54, 0x18}, // e
+	{0x08, 0x7e, 0x09, 0x01, 0x02}, // f
+	{0x08, 0x14, 0x54, 0x54, 0x3c}, // g
+	{0x7f, 0x08, 0x04, 0x04, 0x78}, // h
+	{0x00, 0x44, 0x7d, 0x40, 0x00}, // i
+	{0x20, 0x40, 0x44, 0x3d, 0x00}, // j
+	{0x00, 0x7f, 0x10, 0x28, 0x44}, // k
+	{0x00, 0x41, 0x7f, 0x40, 0x00}, // l
+	{0x7c, 0x04, 0x18, 0x04, 0x78}, // m
+	{0x7c, 0x08, 0x04, 0x04, 0x78}, // n
+	{0x38, 0x44, 0x44, 0x44, 0x38}, // o
+	{0x7c, 0x14, 0x14, 0x14, 0x08}, // p
+	{0x08, 0x14, 0x14, 0x18, 0x7c}, // q
+	{0x7c, 0x08, 0x04, 0x04, 0x08}, // r
+	{0x48, 0x54, 0x54, 0x54, 0x20}, // s
+	{0x04, 0x3f, 0x44, 0x40, 0x20}, // t
+	{0x3c, 0x40, 0x40, 0x20, 0x7c}, // u
+	{0x1c, 0x20, 0x40, 0x20, 0x1c}, // v
+	{0x3c, 0x40, 0x30, 0x40, 0x3c}, // w
+	{0x44, 0x28, 0x10, 0x28, 0x44}, // x
+	{0x0c, 0x50, 0x50, 0x50, 0x3c}, // y
+	{0x44, 0x64, 0x54, 0x4c, 0x44}, // z
+	{0x00, 0x08, 0x36, 0x41, 0x00}, // {
+	{0x00, 0x00, 0x7f, 0x00, 0x00}, // |
+	{0x00, 0x41, 0x36, 0x08, 0x00}, // }
+	{0x08, 0x04, 0x08, 0x10, 0x08}, // ~
+}
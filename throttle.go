@@ -0,0 +1,76 @@
+package strmctrl
+
+import (
+	"time"
+)
+
+// throttle checks if the display button with the given index (1-6) was updated within the interval set with
+// WithMaxFPS. In this case, a delayed update with the latest image state is scheduled and throttle returns true.
+// The lock must be held when calling throttle.
+func (d *Device) throttle(index uint8) bool {
+	interval := d.settings.minUpdateInterval
+	if interval <= 0 {
+		return false
+	}
+	if d.throttled[index-1] {
+		return true
+	}
+	remaining := interval - time.Since(d.updated[index-1])
+	if remaining <= 0 {
+		return false
+	}
+
+	d.throttled[index-1] = true
+	time.AfterFunc(remaining, func() {
+		d.flushThrottled(index)
+	})
+	return true
+}
+
+// throttleUploads checks the display buttons with the given JPEG data like throttle and returns which of them are
+// throttled. The display buttons that would not change and display buttons that are cleared are not throttled.
+// The lock must be held when calling throttleUploads.
+func (d *Device) throttleUploads(jpgs [6][]byte) [6]bool {
+	var result [6]bool
+	if d.settings.minUpdateInterval <= 0 || !d.displayedKnown {
+		return result
+	}
+	for i, jpg := range jpgs {
+		index := uint8(i + 1)
+		if jpg == nil || d.isDisplayed(index, jpg) {
+			continue
+		}
+		result[i] = d.throttle(index)
+	}
+	return result
+}
+
+// keepThrottled replaces the JPEG data of the throttled display buttons with the data that is currently displayed,
+// so that these display buttons are skipped by uploadJPEGs until the delayed update. The lock must be held when
+// calling keepThrottled.
+func (d *Device) keepThrottled(jpgs *[6][]byte) {
+	for i, throttled := range d.throttleUploads(*jpgs) {
+		if throttled {
+			jpgs[i] = d.displayed[i]
+		}
+	}
+}
+
+// flushThrottled sends the latest image state of the display button with the given index (1-6).
+func (d *Device) flushThrottled(index uint8) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.throttled[index-1] = false
+	if d.isClosed() {
+		return
+	}
+
+	if d.images[index-1] == nil && d.jpegs[index-1] == nil {
+		return
+	}
+	err := d.recoverFrom(d.uploadState(d.ops, index))
+	if err != nil {
+		d.settings.logger.Printf("cannot update display %d: %v", index, err)
+	}
+}
@@ -0,0 +1,105 @@
+package strmctrl
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxFPS(t *testing.T) {
+	const (
+		fps     = 10
+		updates = 30
+		pause   = 10 * time.Millisecond
+	)
+	gray := func(i int) image.Image {
+		return uniformImage(color.Gray{Y: uint8(i * 255 / (updates - 1))})
+	}
+
+	tt := []struct {
+		name     string
+		displays int
+		update   func(ctx context.Context, sim *Simulator, i int) error
+	}{
+		{"SetImage", 1, func(ctx context.Context, sim *Simulator, i int) error {
+			return sim.SetImage(ctx, DisplayTopLeft, gray(i))
+		}},
+		{"SetImageJPEG", 1, func(ctx context.Context, sim *Simulator, i int) error {
+			jpg, err := JPEGEncoder{Quality: DefaultJPEGQuality}.Encode(gray(i))
+			if err != nil {
+				return err
+			}
+			return sim.SetImageJPEG(ctx, DisplayTopLeft, jpg)
+		}},
+		{"SetImages", 6, func(ctx context.Context, sim *Simulator, i int) error {
+			var imgs [6]image.Image
+			for j := range imgs {
+				imgs[j] = gray(i)
+			}
+			return sim.SetImages(ctx, imgs)
+		}},
+		{"SetAllImages", 6, func(ctx context.Context, sim *Simulator, i int) error {
+			return sim.SetAllImages(ctx, gray(i))
+		}},
+		{"Batch", 1, func(ctx context.Context, sim *Simulator, i int) error {
+			batch := sim.Begin()
+			err := batch.SetImage(DisplayTopLeft, gray(i))
+			if err != nil {
+				return err
+			}
+			return batch.Commit(ctx)
+		}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			transfers := 0
+			sim := newTestSimulator(t, WithMaxFPS(fps), WithMetrics(func(m Metric) {
+				lock.Lock()
+				defer lock.Unlock()
+				if m.Operation == "BAT" {
+					transfers++
+				}
+			}))
+			ctx := context.Background()
+			// the device state is known after the first update
+			err := sim.Clear(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			for i := range updates {
+				err := tc.update(ctx, sim, i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(pause)
+			}
+			elapsed := time.Since(start)
+			time.Sleep(2 * time.Second / fps)
+
+			lock.Lock()
+			perDisplay := transfers / tc.displays
+			lock.Unlock()
+			maxTransfers := int(elapsed*fps/time.Second) + 2
+			if perDisplay < 2 || perDisplay > maxTransfers {
+				t.Errorf("expected 2 to %d transfers per display, got %d", maxTransfers, perDisplay)
+			}
+			img, err := sim.Image(DisplayTopLeft)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img == nil {
+				t.Fatal("expected an image")
+			}
+			actual := color.GrayModel.Convert(img.At(ImageSize/2, ImageSize/2)).(color.Gray).Y
+			if actual < 0xf8 {
+				t.Errorf("expected the last image to be shown, got gray value %d", actual)
+			}
+		})
+	}
+}
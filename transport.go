@@ -0,0 +1,33 @@
+package strmctrl
+
+import (
+	"context"
+	"time"
+)
+
+// Transport abstracts the endpoints that are used to communicate with the device. The default
+// transport uses the USB endpoints of a Stream Controller SE device, other implementations can be
+// used with OpenTransport, e.g. for testing without real hardware.
+type Transport interface {
+	// Info returns the information about the connected device.
+	Info() DeviceInfo
+	// InEndpoint describes the endpoint that provides the events.
+	InEndpoint() EndpointDesc
+	// OutEndpoint describes the endpoint that receives the commands and images.
+	OutEndpoint() EndpointDesc
+	// Read one packet from the IN endpoint.
+	Read(ctx context.Context, packet []byte) (int, error)
+	// Write one packet, or the rest of a packet, to the OUT endpoint and return the number of bytes that were
+	// written. The buffer is at most as long as the OUT endpoint's MaxPacketSize, data is padded with zeros to
+	// fill a packet. After a short write, the remaining bytes of the packet are passed to Write again in a
+	// shorter buffer.
+	Write(ctx context.Context, packet []byte) (int, error)
+	// Close the transport and release all resources.
+	Close()
+}
+
+// EndpointDesc describes the properties of an endpoint that are relevant for the communication with the device.
+type EndpointDesc struct {
+	MaxPacketSize int
+	PollInterval  time.Duration
+}
@@ -0,0 +1,215 @@
+package strmctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errTestWrite = errors.New("write failed")
+
+// testTransport wraps the transport of the simulator. It fails all writes from the given write on, and counts
+// how often it is closed.
+type testTransport struct {
+	*simTransport
+
+	lock   sync.Mutex
+	failAt int // the first write (1-based) that fails, zero if no write fails
+	writes int
+	closes int
+}
+
+func newTestTransport(failAt int) *testTransport {
+	return &testTransport{simTransport: newSimTransport(), failAt: failAt}
+}
+
+func (t *testTransport) Write(ctx context.Context, packet []byte) (int, error) {
+	t.lock.Lock()
+	t.writes++
+	fail := t.failAt > 0 && t.writes >= t.failAt
+	t.lock.Unlock()
+
+	if fail {
+		return 0, errTestWrite
+	}
+	return t.simTransport.Write(ctx, packet)
+}
+
+func (t *testTransport) Close() {
+	t.lock.Lock()
+	t.closes++
+	t.lock.Unlock()
+
+	t.simTransport.Close()
+}
+
+func (t *testTransport) closeCount() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.closes
+}
+
+func TestOpenTransportRollback(t *testing.T) {
+	steps := []string{"DIS", "CONNECT", "settle CONNECT"}
+	for i, step := range steps {
+		t.Run(fmt.Sprintf("%s fails", step), func(t *testing.T) {
+			transport := newTestTransport(i + 1)
+
+			device, err := OpenTransport(transport, WithSettleTime(time.Millisecond), WithWriteRetries(0), WithLogger(nil))
+
+			if !errors.Is(err, errTestWrite) {
+				t.Errorf("expected the write error, got %v", err)
+			}
+			if device != nil {
+				t.Error("expected no device")
+			}
+			if transport.closeCount() != 1 {
+				t.Errorf("expected the transport to be closed once, got %d", transport.closeCount())
+			}
+		})
+	}
+
+	t.Run("no step fails", func(t *testing.T) {
+		transport := newTestTransport(0)
+
+		device, err := OpenTransport(transport, WithSettleTime(time.Millisecond), WithKeepAliveInterval(0), WithLogger(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transport.closeCount() != 0 {
+			t.Error("expected the transport to stay open")
+		}
+
+		device.Close()
+		if transport.closeCount() != 1 {
+			t.Errorf("expected the transport to be closed once, got %d", transport.closeCount())
+		}
+	})
+}
+
+// shortWriteTransport wraps the transport of the simulator with a small packet size. It accepts at most
+// maxWrite bytes with every write, and passes every complete packet to the simulator.
+type shortWriteTransport struct {
+	*simTransport
+
+	packetSize int
+	maxWrite   int
+
+	lock   sync.Mutex
+	packet []byte
+}
+
+func (t *shortWriteTransport) OutEndpoint() EndpointDesc {
+	return EndpointDesc{MaxPacketSize: t.packetSize}
+}
+
+func (t *shortWriteTransport) Write(ctx context.Context, packet []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(packet) > t.packetSize-len(t.packet) {
+		return 0, fmt.Errorf("%d bytes exceed the packet", len(packet))
+	}
+	n := min(len(packet), t.maxWrite)
+	t.packet = append(t.packet, packet[:n]...)
+	if len(t.packet) < t.packetSize {
+		return n, nil
+	}
+
+	_, err := t.simTransport.Write(ctx, t.packet)
+	t.packet = t.packet[:0]
+	return n, err
+}
+
+func TestWriteDataSmallPackets(t *testing.T) {
+	transport := &shortWriteTransport{simTransport: newSimTransport(), packetSize: 16, maxWrite: 5}
+	device, err := OpenTransport(transport, WithSettleTime(0), WithKeepAliveInterval(0), WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer device.Close()
+	sim := &Simulator{Device: device, transport: transport.simTransport}
+	ctx := context.Background()
+
+	err = sim.SetBrightness(ctx, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sim.SetImage(ctx, DisplayTopLeft, uniformImage(color.White))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sim.RawBrightness() != applyGamma(50, DefaultBrightnessGamma) {
+		t.Errorf("unexpected raw brightness %d", sim.RawBrightness())
+	}
+	img, err := sim.Image(DisplayTopLeft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img == nil {
+		t.Fatal("expected the image to be uploaded")
+	}
+	r, g, b, _ := img.At(ImageSize/2, ImageSize/2).RGBA()
+	if r>>8 < 0xf0 || g>>8 < 0xf0 || b>>8 < 0xf0 {
+		t.Errorf("expected a white image, got %d, %d, %d", r>>8, g>>8, b>>8)
+	}
+	transport.lock.Lock()
+	defer transport.lock.Unlock()
+	if len(transport.packet) != 0 {
+		t.Errorf("expected only complete packets, %d bytes are left", len(transport.packet))
+	}
+}
+
+// noPollTransport wraps the transport of the simulator with an IN endpoint that reports no poll interval.
+type noPollTransport struct {
+	*simTransport
+}
+
+func (t noPollTransport) InEndpoint() EndpointDesc {
+	return EndpointDesc{MaxPacketSize: simulatorPacketSize}
+}
+
+func TestReadEventsWithoutPollInterval(t *testing.T) {
+	tt := []struct {
+		name string
+		opts []Option
+	}{
+		{"polling", nil},
+		{"blocking", []Option{WithBlockingReads()}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := noPollTransport{newSimTransport()}
+			opts := append([]Option{WithSettleTime(0), WithKeepAliveInterval(0), WithLogger(nil)}, tc.opts...)
+			device, err := OpenTransport(transport, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer device.Close()
+			sim := &Simulator{Device: device, transport: transport.simTransport}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			events, err := sim.ReadEvents(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sim.Inject(Event{Control: ButtonRight, Action: Pressed})
+
+			select {
+			case e := <-events:
+				if e.Control != ButtonRight || e.Action != Pressed {
+					t.Errorf("unexpected event %v %v", e.Control, e.Action)
+				}
+			case <-ctx.Done():
+				t.Fatal("no event received")
+			}
+		})
+	}
+}
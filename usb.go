@@ -0,0 +1,326 @@
+package strmctrl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gousb"
+)
+
+// usbTransport communicates with a Stream Controller SE device through the gousb library.
+type usbTransport struct {
+	usb     *gousb.Context
+	ownsUSB bool // the USB context is closed with the transport
+	device  usbDevice
+	info    DeviceInfo
+
+	config usbConfig
+	intf0  usbInterface
+	epIn   *gousb.InEndpoint
+	epOut  *gousb.OutEndpoint
+}
+
+// usbDevice is the part of a gousb.Device that is needed to set up the transport. It allows to test the
+// setup without hardware.
+type usbDevice interface {
+	SetAutoDetach(autodetach bool) error
+	Reset() error
+	Config(num int) (usbConfig, error)
+	Close() error
+}
+
+// usbConfig is the part of a gousb.Config that is needed to set up the transport.
+type usbConfig interface {
+	Interface(num, alt int) (usbInterface, error)
+	Close() error
+}
+
+// usbInterface is the part of a gousb.Interface that is needed to set up the transport.
+type usbInterface interface {
+	InEndpoint(num int) (*gousb.InEndpoint, error)
+	OutEndpoint(num int) (*gousb.OutEndpoint, error)
+	Close()
+}
+
+// gousbDevice adapts a gousb.Device to the usbDevice interface.
+type gousbDevice struct {
+	*gousb.Device
+}
+
+func (d gousbDevice) Config(num int) (usbConfig, error) {
+	config, err := d.Device.Config(num)
+	if err != nil {
+		return nil, err
+	}
+	return gousbConfig{config}, nil
+}
+
+// gousbConfig adapts a gousb.Config to the usbConfig interface.
+type gousbConfig struct {
+	*gousb.Config
+}
+
+func (c gousbConfig) Interface(num, alt int) (usbInterface, error) {
+	intf, err := c.Config.Interface(num, alt)
+	if err != nil {
+		return nil, err
+	}
+	return intf, nil
+}
+
+// openUSBTransport opens the USB device chosen by the given selector and sets up the endpoints.
+// If the given USB context is nil, the transport uses its own USB context.
+func openUSBTransport(ctx context.Context, usb *gousb.Context, id usbID, selector deviceSelector, reset bool) (*usbTransport, error) {
+	ownsUSB := usb == nil
+	if ownsUSB {
+		usb = gousb.NewContext()
+	}
+	success := false
+	defer func() {
+		if !success && ownsUSB {
+			usb.Close()
+		}
+	}()
+
+	device, err := openUSBDevice(ctx, usb, id, selector)
+	if err != nil {
+		return nil, err
+	}
+	info := deviceInfo(device)
+
+	result, err := setupUSBTransport(ctx, gousbDevice{device}, reset)
+	if err != nil {
+		return nil, err
+	}
+	result.usb = usb
+	result.ownsUSB = ownsUSB
+	result.info = info
+
+	success = true
+	return result, nil
+}
+
+// setupUSBTransport prepares the given opened device and sets up the endpoints. If reset is true, the device
+// is reset first. If the setup fails, everything that was acquired is released and the device is closed.
+func setupUSBTransport(ctx context.Context, device usbDevice, reset bool) (*usbTransport, error) {
+	success := false
+	defer func() {
+		if !success {
+			device.Close()
+		}
+	}()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	err := device.SetAutoDetach(true)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set autoDetach: %w", err)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if reset {
+		err = device.Reset()
+		if err != nil {
+			return nil, fmt.Errorf("cannot reset device: %w", err)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	result := &usbTransport{device: device}
+	err = result.setupEndpoints()
+	if err != nil {
+		result.closeEndpoints()
+		return nil, fmt.Errorf("cannot setup endpoints: %w", err)
+	}
+
+	success = true
+	return result, nil
+}
+
+// usbID identifies the kind of device by its USB vendor and product ID.
+type usbID struct {
+	vendor  gousb.ID
+	product gousb.ID
+}
+
+func (id usbID) matches(desc *gousb.DeviceDesc) bool {
+	return desc.Vendor == id.vendor && desc.Product == id.product
+}
+
+// deviceSelector chooses the device to open from the enumerated devices.
+type deviceSelector struct {
+	match       func(*gousb.Device) bool
+	description string
+}
+
+func selectSerial(serial string) deviceSelector {
+	return deviceSelector{
+		match: func(device *gousb.Device) bool {
+			if serial == "" {
+				return true
+			}
+			deviceSerial, err := device.SerialNumber()
+			return err == nil && serial == deviceSerial
+		},
+		description: serialDescription(serial),
+	}
+}
+
+func selectAddress(bus int, address int) deviceSelector {
+	return deviceSelector{
+		match: func(device *gousb.Device) bool {
+			return device.Desc.Bus == bus && device.Desc.Address == address
+		},
+		description: fmt.Sprintf("on bus %03d with address %03d", bus, address),
+	}
+}
+
+// filtered returns a selector that additionally requires the given filter to accept the device. A nil filter
+// accepts all devices.
+func (s deviceSelector) filtered(filter func(DeviceInfo) bool) deviceSelector {
+	if filter == nil {
+		return s
+	}
+	return deviceSelector{
+		match: func(device *gousb.Device) bool {
+			return s.match(device) && filter(deviceInfo(device))
+		},
+		description: s.description + " matching the device filter",
+	}
+}
+
+// deviceInfo collects the information about the given device. Errors reading the string descriptors are ignored.
+func deviceInfo(device *gousb.Device) DeviceInfo {
+	serial, _ := device.SerialNumber()
+	product, _ := device.Product()
+	return DeviceInfo{
+		Bus:     device.Desc.Bus,
+		Address: device.Desc.Address,
+		Serial:  serial,
+		Product: product,
+		Release: device.Desc.Device.String(),
+	}
+}
+
+func serialDescription(serial string) string {
+	if serial == "" {
+		return "any device"
+	}
+	return "with serial " + serial
+}
+
+// openUSBDevice opens the USB device chosen by the given selector. All other devices are closed again.
+func openUSBDevice(ctx context.Context, usb *gousb.Context, id usbID, selector deviceSelector) (*gousb.Device, error) {
+	devices, err := usb.OpenDevices(id.matches)
+	if err != nil {
+		for _, device := range devices {
+			if device != nil {
+				device.Close()
+			}
+		}
+		return nil, fmt.Errorf("cannot find device: %w", err)
+	}
+
+	var foundDevice *gousb.Device
+	for _, device := range devices {
+		if foundDevice != nil {
+			device.Close()
+			continue
+		}
+
+		if selector.match(device) {
+			foundDevice = device
+			continue
+		}
+
+		device.Close()
+	}
+
+	if foundDevice == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, selector.description)
+	}
+	if ctx.Err() != nil {
+		foundDevice.Close()
+		return nil, ctx.Err()
+	}
+
+	return foundDevice, nil
+}
+
+func (t *usbTransport) setupEndpoints() error {
+	var err error
+
+	t.config, err = t.device.Config(1)
+	if err != nil {
+		return fmt.Errorf("cannot open config: %w", err)
+	}
+
+	t.intf0, err = t.config.Interface(0, 0)
+	if err != nil {
+		return fmt.Errorf("cannot get interface: %w", err)
+	}
+
+	t.epIn, err = t.intf0.InEndpoint(2)
+	if err != nil {
+		return fmt.Errorf("cannot create IN endpoint: %w", err)
+	}
+
+	t.epOut, err = t.intf0.OutEndpoint(3)
+	if err != nil {
+		return fmt.Errorf("cannot create OUT endpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (t *usbTransport) Info() DeviceInfo {
+	return t.info
+}
+
+func (t *usbTransport) InEndpoint() EndpointDesc {
+	return EndpointDesc{
+		MaxPacketSize: t.epIn.Desc.MaxPacketSize,
+		PollInterval:  t.epIn.Desc.PollInterval,
+	}
+}
+
+func (t *usbTransport) OutEndpoint() EndpointDesc {
+	return EndpointDesc{
+		MaxPacketSize: t.epOut.Desc.MaxPacketSize,
+		PollInterval:  t.epOut.Desc.PollInterval,
+	}
+}
+
+func (t *usbTransport) Read(ctx context.Context, packet []byte) (int, error) {
+	return t.epIn.ReadContext(ctx, packet)
+}
+
+func (t *usbTransport) Write(ctx context.Context, packet []byte) (int, error) {
+	return t.epOut.WriteContext(ctx, packet)
+}
+
+func (t *usbTransport) Close() {
+	t.closeEndpoints()
+	if t.device != nil {
+		t.device.Close()
+	}
+	if t.usb != nil && t.ownsUSB {
+		t.usb.Close()
+	}
+}
+
+// closeEndpoints releases the interface and the configuration of the device.
+func (t *usbTransport) closeEndpoints() {
+	if t.intf0 != nil {
+		t.intf0.Close()
+		t.intf0 = nil
+	}
+	if t.config != nil {
+		t.config.Close()
+		t.config = nil
+	}
+}
@@ -0,0 +1,130 @@
+package strmctrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/gousb"
+)
+
+var errTestStep = errors.New("step failed")
+
+// fakeUSB simulates the USB device that is set up by setupUSBTransport. It fails the given step and keeps
+// track of the resources that are open.
+type fakeUSB struct {
+	failStep string
+	open     map[string]bool
+}
+
+func (f *fakeUSB) step(name string) error {
+	if name == f.failStep {
+		return errTestStep
+	}
+	return nil
+}
+
+func (f *fakeUSB) acquire(resource string) error {
+	if err := f.step(resource); err != nil {
+		return err
+	}
+	f.open[resource] = true
+	return nil
+}
+
+type fakeUSBDevice struct{ *fakeUSB }
+
+func (d fakeUSBDevice) SetAutoDetach(bool) error { return d.step("SetAutoDetach") }
+func (d fakeUSBDevice) Reset() error             { return d.step("Reset") }
+func (d fakeUSBDevice) Close() error {
+	delete(d.open, "device")
+	return nil
+}
+
+func (d fakeUSBDevice) Config(int) (usbConfig, error) {
+	if err := d.acquire("config"); err != nil {
+		return nil, err
+	}
+	return fakeUSBConfig(d), nil
+}
+
+type fakeUSBConfig struct{ *fakeUSB }
+
+func (c fakeUSBConfig) Interface(int, int) (usbInterface, error) {
+	if err := c.acquire("interface"); err != nil {
+		return nil, err
+	}
+	return fakeUSBInterface(c), nil
+}
+
+func (c fakeUSBConfig) Close() error {
+	delete(c.open, "config")
+	return nil
+}
+
+type fakeUSBInterface struct{ *fakeUSB }
+
+func (i fakeUSBInterface) InEndpoint(int) (*gousb.InEndpoint, error) {
+	return &gousb.InEndpoint{}, i.step("InEndpoint")
+}
+
+func (i fakeUSBInterface) OutEndpoint(int) (*gousb.OutEndpoint, error) {
+	return &gousb.OutEndpoint{}, i.step("OutEndpoint")
+}
+
+func (i fakeUSBInterface) Close() {
+	delete(i.open, "interface")
+}
+
+func TestSetupUSBTransportRollback(t *testing.T) {
+	steps := []string{"SetAutoDetach", "Reset", "config", "interface", "InEndpoint", "OutEndpoint"}
+	for _, step := range steps {
+		t.Run(step+" fails", func(t *testing.T) {
+			usb := &fakeUSB{failStep: step, open: map[string]bool{"device": true}}
+
+			transport, err := setupUSBTransport(context.Background(), fakeUSBDevice{usb}, true)
+
+			if !errors.Is(err, errTestStep) {
+				t.Errorf("expected the error of the step, got %v", err)
+			}
+			if transport != nil {
+				t.Error("expected no transport")
+			}
+			if len(usb.open) != 0 {
+				t.Errorf("expected all resources to be released, still open: %v", usb.open)
+			}
+		})
+	}
+
+	t.Run("context done", func(t *testing.T) {
+		usb := &fakeUSB{open: map[string]bool{"device": true}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := setupUSBTransport(ctx, fakeUSBDevice{usb}, true)
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if len(usb.open) != 0 {
+			t.Errorf("expected all resources to be released, still open: %v", usb.open)
+		}
+	})
+
+	t.Run("no step fails", func(t *testing.T) {
+		usb := &fakeUSB{open: map[string]bool{"device": true}}
+
+		transport, err := setupUSBTransport(context.Background(), fakeUSBDevice{usb}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(usb.open) != 3 {
+			t.Errorf("expected device, config, and interface to be open, got %v", usb.open)
+		}
+
+		transport.Close()
+		if len(usb.open) != 0 {
+			t.Errorf("expected all resources to be released, still open: %v", usb.open)
+		}
+	})
+}
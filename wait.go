@@ -0,0 +1,44 @@
+package strmctrl
+
+import (
+	"context"
+)
+
+// WaitForEvent blocks until the device provides an event that matches the given predicate and returns this
+// event. The events before the matching event are dropped. WaitForEvent reads the events like ReadEvents,
+// it must not be used while the channel provided by ReadEvents is consumed. It returns an error if the given
+// context is done, the device is closed, or reading from the device failed permanently.
+func (d *Device) WaitForEvent(ctx context.Context, predicate func(Event) bool) (Event, error) {
+	if d.isClosed() {
+		return Event{}, ErrClosed
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := d.ReadEvents(ctx)
+	if err != nil {
+		return Event{}, err
+	}
+	defer func() {
+		// stop reading the events and wait until the channel is closed
+		cancel()
+		for range events {
+		}
+	}()
+
+	for e := range events {
+		if predicate(e) {
+			return e, nil
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return Event{}, ctx.Err()
+	case d.Err() != nil:
+		return Event{}, d.Err()
+	default:
+		return Event{}, ErrClosed
+	}
+}
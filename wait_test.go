@@ -0,0 +1,51 @@
+package strmctrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitForEvent(t *testing.T) {
+	sim := newTestSimulator(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		sim.Inject(Event{Control: DisplayTopLeft, Action: Pressed})
+		sim.Inject(Event{Control: DisplayTopLeft, Action: Released})
+	}()
+	e, err := sim.WaitForEvent(ctx, func(e Event) bool { return e.Action == Released })
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Control != DisplayTopLeft || e.Action != Released {
+		t.Errorf("unexpected event %v", e)
+	}
+}
+
+func TestWaitForEventAfterClose(t *testing.T) {
+	sim := newTestSimulator(t)
+	sim.Close()
+
+	_, err := sim.WaitForEvent(context.Background(), func(Event) bool { return true })
+
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestWaitForEventContextDone(t *testing.T) {
+	sim := newTestSimulator(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := sim.WaitForEvent(ctx, func(Event) bool { return true })
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package strmctrl
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	watchInterval = 1 * time.Second
+)
+
+type DeviceEventType uint8
+
+const (
+	DeviceConnected DeviceEventType = iota
+	DeviceDisconnected
+)
+
+// DeviceEvent notifies about a Stream Controller SE device that was connected or disconnected.
+type DeviceEvent struct {
+	Type DeviceEventType
+	Info DeviceInfo
+}
+
+// Watch the USB bus for Stream Controller SE devices that are connected or disconnected.
+// The returned channel first provides a DeviceConnected event for every device that is
+// already connected. The USB bus is polled periodically, the channel is closed when the
+// given context is done. The devices are identified by their bus and address, so a device
+// whose serial number cannot be read temporarily is not reported as disconnected. Problems
+// reading the devices are logged, see WithLogger. The given options are passed to List.
+func Watch(ctx context.Context, opts ...Option) (<-chan DeviceEvent, error) {
+	list := func() ([]DeviceInfo, error) {
+		return List(opts...)
+	}
+	return watch(ctx, list, watchInterval, newSettings(opts).logger)
+}
+
+// watch polls the given list function with the given interval, see Watch.
+func watch(ctx context.Context, list func() ([]DeviceInfo, error), interval time.Duration, logger Logger) (<-chan DeviceEvent, error) {
+	current, err := list()
+	if err != nil && len(current) == 0 {
+		return nil, err
+	}
+
+	lastErr := ""
+	logErr := func(err error) {
+		// log only new problems, the same device would be reported with every poll otherwise
+		if err == nil || err.Error() == lastErr {
+			return
+		}
+		lastErr = err.Error()
+		logger.Printf("cannot read all devices: %v", err)
+	}
+	logErr(err)
+
+	events := make(chan DeviceEvent)
+
+	go func() {
+		defer close(events)
+
+		known := make(map[deviceAddress]DeviceInfo)
+		notify := func(infos []DeviceInfo, eventType DeviceEventType) bool {
+			for _, info := range infos {
+				select {
+				case events <- DeviceEvent{Type: eventType, Info: info}:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for {
+			connected, disconnected := diffDevices(known, current)
+			if !notify(disconnected, DeviceDisconnected) || !notify(connected, DeviceConnected) {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				infos, err := list()
+				logErr(err)
+				if err != nil && len(infos) == 0 { // try again with the next tick
+					continue
+				}
+				current = infos
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// deviceAddress identifies a connected device on the USB bus.
+type deviceAddress struct {
+	bus     int
+	address int
+}
+
+func addressOf(info DeviceInfo) deviceAddress {
+	return deviceAddress{bus: info.Bus, address: info.Address}
+}
+
+// diffDevices updates the set of known devices to the current devices and returns the
+// devices that were connected and disconnected since the last update. The devices are
+// identified by their address, so that a device whose serial number cannot be read
+// temporarily is not reported as disconnected.
+func diffDevices(known map[deviceAddress]DeviceInfo, current []DeviceInfo) (connected []DeviceInfo, disconnected []DeviceInfo) {
+	present := make(map[deviceAddress]bool, len(current))
+	for _, info := range current {
+		address := addressOf(info)
+		present[address] = true
+		if _, ok := known[address]; !ok {
+			connected = append(connected, info)
+			known[address] = info
+		}
+	}
+	for address, info := range known {
+		if !present[address] {
+			disconnected = append(disconnected, info)
+			delete(known, address)
+		}
+	}
+	return connected, disconnected
+}
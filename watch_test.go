@@ -0,0 +1,78 @@
+package strmctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testLogger struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (l *testLogger) Printf(format string, args ...any) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestWatchFlakySerial(t *testing.T) {
+	device := DeviceInfo{Bus: 1, Address: 4, Serial: "1234"}
+	flaky := DeviceInfo{Bus: 1, Address: 4}
+	other := DeviceInfo{Bus: 1, Address: 5, Serial: "5678"}
+	errSerial := errors.New("cannot read the serial number")
+
+	type listResult struct {
+		infos []DeviceInfo
+		err   error
+	}
+	results := make(chan listResult, 10)
+	results <- listResult{[]DeviceInfo{device}, nil}
+	results <- listResult{[]DeviceInfo{flaky}, errSerial}
+	results <- listResult{[]DeviceInfo{flaky}, errSerial}
+	results <- listResult{[]DeviceInfo{device, other}, nil}
+	results <- listResult{[]DeviceInfo{other}, nil}
+	list := func() ([]DeviceInfo, error) {
+		select {
+		case result := <-results:
+			return result.infos, result.err
+		default:
+			return []DeviceInfo{other}, nil
+		}
+	}
+	logger := &testLogger{}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	events, err := watch(ctx, list, time.Millisecond, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []DeviceEvent{
+		{Type: DeviceConnected, Info: device},
+		{Type: DeviceConnected, Info: other},
+		{Type: DeviceDisconnected, Info: device},
+	}
+	for i, e := range expected {
+		select {
+		case actual := <-events:
+			if actual != e {
+				t.Errorf("event %d: expected %v, got %v", i, e, actual)
+			}
+		case <-ctx.Done():
+			t.Fatalf("event %d: expected %v, got nothing", i, e)
+		}
+	}
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	if len(logger.messages) != 1 {
+		t.Errorf("expected the error to be logged once, got %v", logger.messages)
+	}
+}